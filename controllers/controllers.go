@@ -7,13 +7,29 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/qb0C80aE/clay/extension"
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
+const (
+	formatJSON   = "json"
+	formatYAML   = "yaml"
+	formatTOML   = "toml"
+	formatBundle = "bundle"
+)
+
+var formatContentTypes = map[string]string{
+	formatYAML: "application/x-yaml; charset=utf-8",
+	formatTOML: "application/toml; charset=utf-8",
+}
+
 func HookSubmodules() {
 }
 
@@ -35,7 +51,82 @@ type Outputter interface {
 	OutputOptions(c *gin.Context, code int)
 }
 
+func formatOf(mediaType string) string {
+	switch {
+	case strings.Contains(mediaType, formatYAML):
+		return formatYAML
+	case strings.Contains(mediaType, formatTOML):
+		return formatTOML
+	default:
+		return formatJSON
+	}
+}
+
+func requestFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	return formatOf(c.Request.Header.Get("Content-Type"))
+}
+
+func responseFormat(c *gin.Context) string {
+	// Only designs are written as bundles, and the other responses of bundle requests follow Accept.
+	if format := c.Query("format"); format != "" && format != formatBundle {
+		return format
+	}
+	return formatOf(c.Request.Header.Get("Accept"))
+}
+
+func invalidFormatError(format string) error {
+	return fmt.Errorf("Invalid Parameter. format must be json, yaml or toml, but '%s'.", format)
+}
+
+func (this *BaseController) render(c *gin.Context, code int, result interface{}) {
+	var data []byte
+	var err error
+
+	format := responseFormat(c)
+	switch format {
+	case formatJSON:
+		if _, ok := c.GetQuery("pretty"); ok {
+			c.IndentedJSON(code, result)
+		} else {
+			c.JSON(code, result)
+		}
+		return
+	case formatYAML:
+		data, err = helper.MarshalYAML(result)
+	case formatTOML:
+		data, err = helper.MarshalTOML(result)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalidFormatError(format).Error()})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(code, formatContentTypes[format], data)
+}
+
 func (this *BaseController) bind(c *gin.Context, container interface{}) error {
+	switch format := requestFormat(c); format {
+	case formatJSON:
+	case formatYAML, formatTOML:
+		data, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		if format == formatYAML {
+			return helper.UnmarshalYAML(data, container)
+		}
+		return helper.UnmarshalTOML(data, container)
+	default:
+		return invalidFormatError(format)
+	}
+
 	if err := c.Bind(container); err != nil {
 		return err
 	}
@@ -86,17 +177,89 @@ func (this *BaseController) bind(c *gin.Context, container interface{}) error {
 	return nil
 }
 
+func (this *BaseController) pageUrl(c *gin.Context, parameters map[string]string) string {
+	reqScheme := "http"
+
+	if c.Request.TLS != nil {
+		reqScheme = "https"
+	}
+
+	query := c.Request.URL.Query()
+	for key, value := range parameters {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+
+	return fmt.Sprintf("%s://%s%s?%s", reqScheme, c.Request.Host, c.Request.URL.Path, query.Encode())
+}
+
+func (this *BaseController) setPaginationHeaders(c *gin.Context, db *gorm.DB, pagination *dbpkg.Pagination, total int, result []interface{}) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	if pagination.Limit == 0 {
+		return
+	}
+
+	limit := pagination.Limit
+	links := []string{}
+
+	if pagination.After != "" {
+		if len(result) == limit {
+			scope := db.NewScope(result[len(result)-1])
+			if !scope.PrimaryKeyZero() {
+				links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", this.pageUrl(c, map[string]string{"after": fmt.Sprint(scope.PrimaryKeyValue())})))
+			}
+		}
+	} else {
+		offset := pagination.Offset
+		if offset+limit < total {
+			links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", this.pageUrl(c, map[string]string{"offset": strconv.Itoa(offset + limit)})))
+		}
+		if offset > 0 {
+			prevOffset := offset - limit
+			if prevOffset < 0 {
+				prevOffset = 0
+			}
+			links = append(links, fmt.Sprintf("<%s>; rel=\"prev\"", this.pageUrl(c, map[string]string{"offset": strconv.Itoa(prevOffset)})))
+		}
+		links = append(links, fmt.Sprintf("<%s>; rel=\"first\"", this.pageUrl(c, map[string]string{"offset": ""})))
+		if total > 0 {
+			links = append(links, fmt.Sprintf("<%s>; rel=\"last\"", this.pageUrl(c, map[string]string{"offset": strconv.Itoa((total - 1) / limit * limit)})))
+		}
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+func (this *BaseController) outputArchive(c *gin.Context, name string, archive string, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, helper.ArchiveFileExtension(archive)))
+	c.Data(http.StatusOK, helper.ArchiveContentType(archive), data)
+}
+
 func (this *BaseController) GetResourceName() string {
 	return this.ResourceName
 }
 
 func (this *BaseController) OutputError(c *gin.Context, code int, err error) {
-	c.JSON(code, gin.H{"error": err.Error()})
+	if statusCodeError, ok := err.(extension.StatusCodeError); ok {
+		response := gin.H{"error": err.Error()}
+		for key, value := range statusCodeError.GetErrorDetails() {
+			response[key] = value
+		}
+		this.render(c, statusCodeError.GetStatusCode(), response)
+		return
+	}
+	this.render(c, code, gin.H{"error": err.Error()})
 }
 
 func (this *BaseController) OutputGetSingle(c *gin.Context, code int, result interface{}, fields map[string]interface{}) {
 	if fields == nil {
-		c.JSON(code, result)
+		this.render(c, code, result)
 	} else {
 		fieldMap, err := helper.FieldToMap(result, fields)
 		if err != nil {
@@ -104,17 +267,13 @@ func (this *BaseController) OutputGetSingle(c *gin.Context, code int, result int
 			return
 		}
 
-		if _, ok := c.GetQuery("pretty"); ok {
-			c.IndentedJSON(code, fieldMap)
-		} else {
-			c.JSON(code, fieldMap)
-		}
+		this.render(c, code, fieldMap)
 	}
 }
 
 func (this *BaseController) OutputGetMulti(c *gin.Context, code int, result []interface{}, fields map[string]interface{}) {
 	if fields == nil {
-		c.JSON(code, result)
+		this.render(c, code, result)
 	} else {
 		if _, ok := c.GetQuery("stream"); ok {
 			enc := json.NewEncoder(c.Writer)
@@ -147,11 +306,7 @@ func (this *BaseController) OutputGetMulti(c *gin.Context, code int, result []in
 				fieldMaps = append(fieldMaps, fieldMap)
 			}
 
-			if _, ok := c.GetQuery("pretty"); ok {
-				c.IndentedJSON(code, fieldMaps)
-			} else {
-				c.JSON(code, fieldMaps)
-			}
+			this.render(c, code, fieldMaps)
 		}
 	}
 }
@@ -197,17 +352,41 @@ func (this *BaseController) GetMulti(c *gin.Context) {
 
 	db := dbpkg.DBInstance(c)
 	db = dbpkg.SetPreloads(c.Query("preloads"), db)
-	db = dbpkg.SortRecords(c.Query("sort"), db)
-	db = dbpkg.FilterFields(c, this.Model, db)
+	db, err := dbpkg.SortRecords(c.Query("sort"), this.Model, db)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+	db, err = dbpkg.FilterFields(c, this.Model, db)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
 	fields := helper.ParseFields(c.DefaultQuery("fields", "*"))
 	queryFields := helper.QueryFields(this.Model, fields)
 
+	pagination, err := dbpkg.ParsePagination(c)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := dbpkg.CountRecords(this.Model, db)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	db = dbpkg.PageRecords(pagination, this.Model, db)
+
 	result, err := this.Logic.GetMulti(db, queryFields)
 	if err != nil {
 		this.OutputError(c, http.StatusBadRequest, err)
 		return
 	}
 
+	this.setPaginationHeaders(c, db, pagination, total, result)
+
 	this.Outputter.OutputGetMulti(c, http.StatusOK, result, fields)
 }
 
@@ -242,6 +421,7 @@ func (this *BaseController) Create(c *gin.Context) {
 	}
 
 	db.Commit()
+	extension.NotifyModelChanged()
 
 	this.Outputter.OutputCreate(c, http.StatusCreated, result)
 }
@@ -279,6 +459,7 @@ func (this *BaseController) Update(c *gin.Context) {
 	}
 
 	db.Commit()
+	extension.NotifyModelChanged()
 
 	this.Outputter.OutputUpdate(c, http.StatusOK, result)
 }
@@ -297,6 +478,7 @@ func (this *BaseController) Delete(c *gin.Context) {
 	}
 
 	db.Commit()
+	extension.NotifyModelChanged()
 
 	this.Outputter.OutputDelete(c, http.StatusNoContent)
 }
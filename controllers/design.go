@@ -1,15 +1,21 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/qb0C80aE/clay/db"
 	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/helper"
 	"github.com/qb0C80aE/clay/logics"
 	"github.com/qb0C80aE/clay/models"
+	"io/ioutil"
+	"net/http"
 )
 
 type DesignController struct {
 	BaseController
+	SnapshotController *BaseController
 }
 
 func init() {
@@ -25,34 +31,248 @@ func NewDesignController() *DesignController {
 func (this *DesignController) Initialize() {
 	this.ResourceName = "design"
 	this.Model = models.DesignModel
-	this.Logic = logics.NewDesignLogic()
+	this.Logic = logics.DesignLogicInstance
 	this.Outputter = this
+
+	this.SnapshotController = &BaseController{
+		ResourceName: "design_snapshot",
+		Model:        models.DesignSnapshotModel,
+		Logic:        logics.DesignSnapshotLogicInstance,
+	}
+	this.SnapshotController.Outputter = this.SnapshotController
 }
 
 func (this *DesignController) GetRouteMap() map[int]map[string]gin.HandlerFunc {
-	url := "designs/present"
+	// The present design, the snapshots and the validation share designs/:id, where :id is "present", "validate" or a snapshot id.
+	resourceSingleUrl := extension.GetResourceSingleUrl(this.ResourceName)
+	resourceMultiUrl := extension.GetResourceMultiUrl(this.ResourceName)
+	diffUrl := resourceSingleUrl + "/diff/:other"
+	diffWithUrl := resourceSingleUrl + "/diff"
+
 	routeMap := map[int]map[string]gin.HandlerFunc{
 		extension.MethodGet: {
-			url: this.GetSingle,
+			resourceSingleUrl: this.GetSingle,
+			resourceMultiUrl:  this.GetMulti,
+			diffUrl:           this.Diff,
+		},
+		extension.MethodPost: {
+			resourceMultiUrl:  this.Create,
+			resourceSingleUrl: this.Validate,
+			diffWithUrl:       this.DiffWith,
 		},
 		extension.MethodPut: {
-			url: this.Update,
+			resourceSingleUrl: this.Update,
 		},
 		extension.MethodDelete: {
-			url: this.Delete,
+			resourceSingleUrl: this.Delete,
 		},
 	}
 	return routeMap
 }
 
+func isBundleRequest(c *gin.Context) bool {
+	if c.Query("format") == formatBundle {
+		return true
+	}
+
+	switch c.Request.Header.Get("Content-Type") {
+	case "application/gzip", "application/x-gzip", "application/x-tar", "application/zip":
+		return true
+	default:
+		return false
+	}
+}
+
+func (this *DesignController) GetSingle(c *gin.Context) {
+	if c.Params.ByName("id") != logics.PresentDesignID {
+		this.SnapshotController.GetSingle(c)
+		return
+	}
+
+	if _, optional := c.GetQuery("optional"); optional {
+		db.SetDBInstance(c, db.DBInstance(c).Set(logics.DesignOptionalContentKey, true))
+	}
+
+	if c.Query("format") == formatBundle {
+		this.GetBundle(c)
+		return
+	}
+
+	this.BaseController.GetSingle(c)
+}
+
+func (this *DesignController) GetBundle(c *gin.Context) {
+	database := db.DBInstance(c)
+
+	design, err := this.Logic.GetSingle(database, logics.PresentDesignID, "*")
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	archive := c.DefaultQuery("archive", helper.ArchiveTar)
+	data, err := logics.DesignLogicInstance.ExportBundle(design.(*models.Design), archive)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.outputArchive(c, "design", archive, data)
+}
+
+func (this *DesignController) GetMulti(c *gin.Context) {
+	this.SnapshotController.GetMulti(c)
+}
+
+func (this *DesignController) Create(c *gin.Context) {
+	this.SnapshotController.Create(c)
+}
+
+func (this *DesignController) Diff(c *gin.Context) {
+	database := db.DBInstance(c)
+
+	result, err := logics.DesignLogicInstance.Diff(database, c.Params.ByName("id"), c.Params.ByName("other"))
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.Outputter.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *DesignController) DiffWith(c *gin.Context) {
+	container := &models.Design{}
+
+	if err := this.bind(c, container); err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	database := db.DBInstance(c)
+
+	result, err := logics.DesignLogicInstance.DiffWith(database, c.Params.ByName("id"), container)
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.Outputter.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *DesignController) Validate(c *gin.Context) {
+	if c.Params.ByName("id") != "validate" {
+		this.OutputError(c, http.StatusNotFound, errors.New("Only the design validation can be posted to."))
+		return
+	}
+
+	container := &models.Design{}
+
+	if err := this.bind(c, container); err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	database := db.DBInstance(c)
+
+	result, err := logics.DesignLogicInstance.Validate(database, container)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if !result.Valid {
+		this.Outputter.OutputGetSingle(c, http.StatusBadRequest, result, nil)
+		return
+	}
+
+	this.Outputter.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
 func (this *DesignController) Update(c *gin.Context) {
-	db.DBInstance(c).Exec("pragma foreign_keys = off;")
-	this.BaseController.Update(c)
-	db.DBInstance(c).Exec("pragma foreign_keys = on;")
+	if c.Params.ByName("id") != logics.PresentDesignID {
+		this.OutputError(c, http.StatusMethodNotAllowed, errors.New("Only the present design can be updated."))
+		return
+	}
+
+	mode := c.DefaultQuery("mode", logics.DesignImportModeReplace)
+
+	database := db.DBInstance(c)
+
+	container := &models.Design{}
+
+	if from := c.Query("from"); from != "" {
+		design, err := logics.DesignLogicInstance.LoadDesign(database, from)
+		if err != nil {
+			this.OutputError(c, http.StatusNotFound, err)
+			return
+		}
+		container = design
+	} else if isBundleRequest(c) {
+		data, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			this.OutputError(c, http.StatusBadRequest, err)
+			return
+		}
+		design, err := logics.DesignLogicInstance.ImportBundle(data)
+		if err != nil {
+			this.OutputError(c, http.StatusBadRequest, err)
+			return
+		}
+		container = design
+	} else if err := this.bind(c, container); err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var result interface{}
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		var err error
+		result, err = logics.DesignLogicInstance.Import(tx, container, mode)
+		return err
+	})
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+	extension.NotifyModelChanged()
+
+	this.Outputter.OutputUpdate(c, http.StatusOK, result)
 }
 
 func (this *DesignController) Delete(c *gin.Context) {
-	db.DBInstance(c).Exec("pragma foreign_keys = off;")
-	this.BaseController.Delete(c)
-	db.DBInstance(c).Exec("pragma foreign_keys = on;")
+	if c.Params.ByName("id") != logics.PresentDesignID {
+		this.DeleteSnapshot(c)
+		return
+	}
+
+	database := db.DBInstance(c)
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		return this.Logic.Delete(tx, "")
+	})
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+	extension.NotifyModelChanged()
+
+	this.Outputter.OutputDelete(c, http.StatusNoContent)
+}
+
+func (this *DesignController) DeleteSnapshot(c *gin.Context) {
+	database := db.DBInstance(c)
+
+	err := db.Transaction(database, func(tx *gorm.DB) error {
+		return this.SnapshotController.Logic.Delete(tx, c.Params.ByName("id"))
+	})
+	if err == gorm.ErrRecordNotFound {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.SnapshotController.Outputter.OutputDelete(c, http.StatusNoContent)
 }
@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"github.com/gin-gonic/gin"
+	dbpkg "github.com/qb0C80aE/clay/db"
 	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/helper"
 	"github.com/qb0C80aE/clay/logics"
 	"github.com/qb0C80aE/clay/models"
+	"net/http"
 )
 
 type TemplateExternalParameterController struct {
@@ -15,9 +18,29 @@ type TemplateController struct {
 	BaseController
 }
 
+type TemplateOutputTargetController struct {
+	BaseController
+}
+
+type TemplateParameterCacheController struct {
+	BaseController
+}
+
+type ParameterSetController struct {
+	BaseController
+}
+
+type TemplateParameterSetController struct {
+	BaseController
+}
+
 func init() {
 	extension.RegisterController(NewTemplateExternalParameterController())
 	extension.RegisterController(NewTemplateController())
+	extension.RegisterController(NewTemplateOutputTargetController())
+	extension.RegisterController(NewTemplateParameterCacheController())
+	extension.RegisterController(NewParameterSetController())
+	extension.RegisterController(NewTemplateParameterSetController())
 }
 
 func NewTemplateExternalParameterController() *TemplateExternalParameterController {
@@ -78,11 +101,19 @@ func (this *TemplateController) GetRouteMap() map[int]map[string]gin.HandlerFunc
 
 	routeMap := map[int]map[string]gin.HandlerFunc{
 		extension.MethodGet: {
-			resourceSingleUrl: this.GetSingle,
-			resourceMultiUrl:  this.GetMulti,
+			resourceSingleUrl:                                      this.GetSingle,
+			resourceMultiUrl:                                       this.GetMulti,
+			resourceSingleUrl + "/parameters":                      this.GetParameters,
+			resourceSingleUrl + "/revisions":                       this.GetRevisions,
+			resourceSingleUrl + "/revisions/:revision":             this.GetRevision,
+			resourceSingleUrl + "/revisions/:revision/diff/:other": this.DiffRevisions,
 		},
 		extension.MethodPost: {
-			resourceMultiUrl: this.Create,
+			resourceMultiUrl:                                   this.Create,
+			resourceSingleUrl + "/render":                      this.Render,
+			resourceSingleUrl + "/publish":                     this.Publish,
+			resourceSingleUrl + "/revisions/:revision/render":  this.RenderRevision,
+			resourceSingleUrl + "/revisions/:revision/restore": this.RestoreRevision,
 		},
 		extension.MethodPut: {
 			resourceSingleUrl: this.Update,
@@ -92,6 +123,7 @@ func (this *TemplateController) GetRouteMap() map[int]map[string]gin.HandlerFunc
 		},
 		extension.MethodPatch: {
 			resourceSingleUrl: this.Patch,
+			resourceMultiUrl:  this.PatchMulti,
 		},
 	}
 	return routeMap
@@ -101,3 +133,353 @@ func (this *TemplateController) OutputPatch(c *gin.Context, code int, result int
 	text := result.(string)
 	c.String(code, text)
 }
+
+// bindParameters binds the parameters of the render, which can be omitted.
+func (this *TemplateController) bindParameters(c *gin.Context) (map[string]interface{}, error) {
+	parameters := map[string]interface{}{}
+	if c.Request.ContentLength != 0 {
+		if err := this.bind(c, &parameters); err != nil {
+			return nil, err
+		}
+	}
+	return parameters, nil
+}
+
+func (this *TemplateController) Render(c *gin.Context) {
+	id := c.Params.ByName("id")
+
+	parameters, err := this.bindParameters(c)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	_, strict := c.GetQuery("strict")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.Render(db, id, parameters, strict)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.OutputPatch(c, http.StatusOK, result)
+}
+
+func (this *TemplateController) Publish(c *gin.Context) {
+	id := c.Params.ByName("id")
+
+	parameters, err := this.bindParameters(c)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	_, strict := c.GetQuery("strict")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.Publish(db, id, parameters, strict)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	extension.NotifyModelChanged()
+
+	this.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *TemplateController) GetParameters(c *gin.Context) {
+	id := c.Params.ByName("id")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.Parameters(db, id)
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *TemplateController) GetRevisions(c *gin.Context) {
+	id := c.Params.ByName("id")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.GetRevisions(db, id)
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *TemplateController) GetRevision(c *gin.Context) {
+	id := c.Params.ByName("id")
+	revision := c.Params.ByName("revision")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.GetRevision(db, id, revision)
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.OutputGetSingle(c, http.StatusOK, result, nil)
+}
+
+func (this *TemplateController) DiffRevisions(c *gin.Context) {
+	id := c.Params.ByName("id")
+	revision := c.Params.ByName("revision")
+	other := c.Params.ByName("other")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.DiffRevisions(db, id, revision, other)
+	if err != nil {
+		this.OutputError(c, http.StatusNotFound, err)
+		return
+	}
+
+	this.OutputPatch(c, http.StatusOK, result)
+}
+
+func (this *TemplateController) RenderRevision(c *gin.Context) {
+	id := c.Params.ByName("id")
+	revision := c.Params.ByName("revision")
+
+	parameters, err := this.bindParameters(c)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	_, strict := c.GetQuery("strict")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.RenderRevision(db, id, revision, parameters, strict)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.OutputPatch(c, http.StatusOK, result)
+}
+
+func (this *TemplateController) RestoreRevision(c *gin.Context) {
+	id := c.Params.ByName("id")
+	revision := c.Params.ByName("revision")
+
+	db := dbpkg.DBInstance(c)
+
+	db = db.Begin()
+	result, err := logics.TemplateLogicInstance.RestoreRevision(db, id, revision)
+	if err != nil {
+		db.Rollback()
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	db.Commit()
+	extension.NotifyModelChanged()
+
+	this.OutputUpdate(c, http.StatusOK, result)
+}
+
+func (this *TemplateController) Patch(c *gin.Context) {
+	id := c.Params.ByName("id")
+	_, strict := c.GetQuery("strict")
+
+	db := dbpkg.DBInstance(c)
+
+	result, err := logics.TemplateLogicInstance.Render(db, id, nil, strict)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.OutputPatch(c, http.StatusOK, result)
+}
+
+func (this *TemplateController) PatchMulti(c *gin.Context) {
+	db := dbpkg.DBInstance(c)
+
+	selection, err := dbpkg.SortRecords(c.Query("sort"), this.Model, db)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+	selection, err = dbpkg.FilterFields(c, this.Model, selection)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := this.Logic.GetMulti(selection, "*")
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	templates := make([]*models.Template, len(result))
+	for i, data := range result {
+		templates[i] = data.(*models.Template)
+	}
+
+	archive := c.DefaultQuery("archive", helper.ArchiveTar)
+	_, strict := c.GetQuery("strict")
+
+	data, err := logics.TemplateLogicInstance.RenderArchive(db, templates, archive, strict)
+	if err != nil {
+		this.OutputError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	this.outputArchive(c, "templates", archive, data)
+}
+
+func NewTemplateOutputTargetController() *TemplateOutputTargetController {
+	controller := &TemplateOutputTargetController{}
+	controller.Initialize()
+	return controller
+}
+
+func (this *TemplateOutputTargetController) Initialize() {
+	this.ResourceName = "template_output_target"
+	this.Model = models.TemplateOutputTargetModel
+	this.Logic = logics.TemplateOutputTargetLogicInstance
+	this.Outputter = this
+}
+
+func (this *TemplateOutputTargetController) GetRouteMap() map[int]map[string]gin.HandlerFunc {
+	resourceSingleUrl := extension.GetResourceSingleUrl(this.ResourceName)
+	resourceMultiUrl := extension.GetResourceMultiUrl(this.ResourceName)
+
+	routeMap := map[int]map[string]gin.HandlerFunc{
+		extension.MethodGet: {
+			resourceSingleUrl: this.GetSingle,
+			resourceMultiUrl:  this.GetMulti,
+		},
+		extension.MethodPost: {
+			resourceMultiUrl: this.Create,
+		},
+		extension.MethodPut: {
+			resourceSingleUrl: this.Update,
+		},
+		extension.MethodDelete: {
+			resourceSingleUrl: this.Delete,
+		},
+	}
+	return routeMap
+}
+
+func NewTemplateParameterCacheController() *TemplateParameterCacheController {
+	controller := &TemplateParameterCacheController{}
+	controller.Initialize()
+	return controller
+}
+
+func (this *TemplateParameterCacheController) Initialize() {
+	this.ResourceName = "template_parameter_cache"
+	this.Outputter = this
+}
+
+func (this *TemplateParameterCacheController) GetRouteMap() map[int]map[string]gin.HandlerFunc {
+	resourceMultiUrl := extension.GetResourceMultiUrl(this.ResourceName)
+
+	routeMap := map[int]map[string]gin.HandlerFunc{
+		extension.MethodGet: {
+			resourceMultiUrl: this.GetStats,
+		},
+		extension.MethodDelete: {
+			resourceMultiUrl: this.Invalidate,
+		},
+	}
+	return routeMap
+}
+
+func (this *TemplateParameterCacheController) GetStats(c *gin.Context) {
+	this.OutputGetSingle(c, http.StatusOK, logics.TemplateParameterCacheInstance.Stats(), nil)
+}
+
+func (this *TemplateParameterCacheController) Invalidate(c *gin.Context) {
+	logics.TemplateParameterCacheInstance.Invalidate()
+	this.OutputDelete(c, http.StatusNoContent)
+}
+
+func NewParameterSetController() *ParameterSetController {
+	controller := &ParameterSetController{}
+	controller.Initialize()
+	return controller
+}
+
+func (this *ParameterSetController) Initialize() {
+	this.ResourceName = "parameter_set"
+	this.Model = models.ParameterSetModel
+	this.Logic = logics.ParameterSetLogicInstance
+	this.Outputter = this
+}
+
+func (this *ParameterSetController) GetRouteMap() map[int]map[string]gin.HandlerFunc {
+	resourceSingleUrl := extension.GetResourceSingleUrl(this.ResourceName)
+	resourceMultiUrl := extension.GetResourceMultiUrl(this.ResourceName)
+
+	routeMap := map[int]map[string]gin.HandlerFunc{
+		extension.MethodGet: {
+			resourceSingleUrl: this.GetSingle,
+			resourceMultiUrl:  this.GetMulti,
+		},
+		extension.MethodPost: {
+			resourceMultiUrl: this.Create,
+		},
+		extension.MethodPut: {
+			resourceSingleUrl: this.Update,
+		},
+		extension.MethodDelete: {
+			resourceSingleUrl: this.Delete,
+		},
+	}
+	return routeMap
+}
+
+func NewTemplateParameterSetController() *TemplateParameterSetController {
+	controller := &TemplateParameterSetController{}
+	controller.Initialize()
+	return controller
+}
+
+func (this *TemplateParameterSetController) Initialize() {
+	this.ResourceName = "template_parameter_set"
+	this.Model = models.TemplateParameterSetModel
+	this.Logic = logics.TemplateParameterSetLogicInstance
+	this.Outputter = this
+}
+
+func (this *TemplateParameterSetController) GetRouteMap() map[int]map[string]gin.HandlerFunc {
+	resourceSingleUrl := extension.GetResourceSingleUrl(this.ResourceName)
+	resourceMultiUrl := extension.GetResourceMultiUrl(this.ResourceName)
+
+	routeMap := map[int]map[string]gin.HandlerFunc{
+		extension.MethodGet: {
+			resourceSingleUrl: this.GetSingle,
+			resourceMultiUrl:  this.GetMulti,
+		},
+		extension.MethodPost: {
+			resourceMultiUrl: this.Create,
+		},
+		extension.MethodPut: {
+			resourceSingleUrl: this.Update,
+		},
+		extension.MethodDelete: {
+			resourceSingleUrl: this.Delete,
+		},
+	}
+	return routeMap
+}
@@ -3,7 +3,6 @@ package db
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/qb0C80aE/clay/extension"
 	"github.com/serenize/snaker"
 	"log"
@@ -11,44 +10,61 @@ import (
 	"strings"
 )
 
-func Connect() *gorm.DB {
-	dbMode := os.Getenv("DB_MODE")
-	var dbPath string
-	switch dbMode {
-	case "", "memory":
-		dbPath = ":memory:"
-	case "file":
-		if dbFilePath := os.Getenv("DB_FILE_PATH"); dbFilePath != "" {
-			dbPath = dbFilePath
-		} else {
-			dbPath = "clay.db"
-		}
-	default:
-		log.Fatalf("Invalid DB_MODE '%s'", dbMode)
+func Open() *gorm.DB {
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver == "" {
+		dbDriver = "sqlite3"
 	}
 
-	db, err := gorm.Open("sqlite3", dbPath)
+	dialect, exists := dialects[dbDriver]
+	if !exists {
+		log.Fatalf("Invalid DB_DRIVER '%s'", dbDriver)
+	}
 
+	dataSourceName, err := dialect.DataSourceName()
 	if err != nil {
 		log.Fatalf("Got error when connect database, the error is '%v'", err)
 	}
 
-	db.Exec("pragma foreign_keys = on")
+	db, err := gorm.Open(dialect.GetName(), dataSourceName)
+
+	if err != nil {
+		log.Fatalf("Got error when connect database, the error is '%v'", err)
+	}
+
+	if err := dialect.Initialize(db); err != nil {
+		log.Fatalf("Got error when initialize database, the error is '%v'", err)
+	}
 	db.LogMode(true)
 
 	if gin.IsDebugging() {
 		db.LogMode(true)
 	}
 
-	db.AutoMigrate(extension.GetModels()...)
+	return db
+}
+
+func Connect() *gorm.DB {
+	db := Open()
+
+	if err := migrateSchema(db, extension.GetModels(), extension.GetMigrations()); err != nil {
+		log.Fatalf("Got error when migrate database, the error is '%v'", err)
+	}
 
 	return db
 }
 
+// AuthorKey is the key of the author of the request, which is set to the db instance with gorm.DB.Set.
+const AuthorKey = "clay:author"
+
 func DBInstance(c *gin.Context) *gorm.DB {
 	return c.MustGet("DB").(*gorm.DB)
 }
 
+func SetDBInstance(c *gin.Context, db *gorm.DB) {
+	c.Set("DB", db)
+}
+
 func SetPreloads(preloads string, db *gorm.DB) *gorm.DB {
 	if preloads == "" {
 		return db
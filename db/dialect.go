@@ -0,0 +1,35 @@
+package db
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+type Dialect interface {
+	GetName() string
+	DataSourceName() (string, error)
+	Initialize(*gorm.DB) error
+}
+
+var dialects = map[string]Dialect{}
+
+func RegisterDialect(dialect Dialect) {
+	dialects[dialect.GetName()] = dialect
+}
+
+func GetDialect(db *gorm.DB) Dialect {
+	return dialects[db.Dialect().GetName()]
+}
+
+func Transaction(db *gorm.DB, f func(*gorm.DB) error) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
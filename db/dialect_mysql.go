@@ -0,0 +1,31 @@
+package db
+
+import (
+	"errors"
+	"os"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+type mysqlDialect struct {
+}
+
+func (_ *mysqlDialect) GetName() string {
+	return "mysql"
+}
+
+func (_ *mysqlDialect) DataSourceName() (string, error) {
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		return dsn, nil
+	}
+	return "", errors.New("DB_DSN is required for mysql")
+}
+
+func (_ *mysqlDialect) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+func init() {
+	RegisterDialect(&mysqlDialect{})
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"errors"
+	"os"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type postgresDialect struct {
+}
+
+func (_ *postgresDialect) GetName() string {
+	return "postgres"
+}
+
+func (_ *postgresDialect) DataSourceName() (string, error) {
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		return dsn, nil
+	}
+	return "", errors.New("DB_DSN is required for postgres")
+}
+
+func (_ *postgresDialect) Initialize(_ *gorm.DB) error {
+	return nil
+}
+
+func init() {
+	RegisterDialect(&postgresDialect{})
+}
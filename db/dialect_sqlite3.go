@@ -0,0 +1,46 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+type sqlite3Dialect struct {
+}
+
+func (_ *sqlite3Dialect) GetName() string {
+	return "sqlite3"
+}
+
+func (_ *sqlite3Dialect) DataSourceName() (string, error) {
+	if dsn := os.Getenv("DB_DSN"); dsn != "" {
+		return dsn, nil
+	}
+
+	dbMode := os.Getenv("DB_MODE")
+	switch dbMode {
+	case "", "memory":
+		return ":memory:", nil
+	case "file":
+		if dbFilePath := os.Getenv("DB_FILE_PATH"); dbFilePath != "" {
+			return dbFilePath, nil
+		}
+		return "clay.db", nil
+	default:
+		return "", fmt.Errorf("Invalid DB_MODE '%s'", dbMode)
+	}
+}
+
+func (_ *sqlite3Dialect) Initialize(db *gorm.DB) error {
+	// foreign_keys is a setting of a connection, and every connection to :memory: is a different database,
+	// so the pool is limited to the one connection the pragma is executed on.
+	db.DB().SetMaxOpenConns(1)
+	return db.Exec("pragma foreign_keys = on;").Error
+}
+
+func init() {
+	RegisterDialect(&sqlite3Dialect{})
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+type DialectTestParent struct {
+	ID int `gorm:"primary_key"`
+}
+
+type DialectTestChild struct {
+	ID                  int `gorm:"primary_key"`
+	DialectTestParentID int `sql:"type:integer references dialect_test_parents(id)"`
+}
+
+func dialectTestDataSourceNames() map[string]string {
+	return map[string]string{
+		"sqlite3":  ":memory:",
+		"postgres": os.Getenv("CLAY_TEST_POSTGRES_DSN"),
+		"mysql":    os.Getenv("CLAY_TEST_MYSQL_DSN"),
+	}
+}
+
+func openDialectTestDB(t *testing.T, name string, dataSourceName string) *gorm.DB {
+	db, err := gorm.Open(name, dataSourceName)
+	if err != nil {
+		t.Fatalf("gorm.Open should not fail. error: %v", err)
+	}
+	// Every connection to :memory: is a different database.
+	db.DB().SetMaxOpenConns(1)
+
+	if err := GetDialect(db).Initialize(db); err != nil {
+		t.Fatalf("Initialize should not fail. error: %v", err)
+	}
+
+	db.DropTableIfExists(&DialectTestChild{}, &DialectTestParent{})
+	if err := db.CreateTable(&DialectTestParent{}, &DialectTestChild{}).Error; err != nil {
+		t.Fatalf("CreateTable should not fail. error: %v", err)
+	}
+	if name == "mysql" {
+		// MySQL ignores inline references.
+		db.Model(&DialectTestChild{}).AddForeignKey("dialect_test_parent_id", "dialect_test_parents(id)", "RESTRICT", "RESTRICT")
+	}
+
+	return db
+}
+
+func TestDialect_Conformance(t *testing.T) {
+	for name, dataSourceName := range dialectTestDataSourceNames() {
+		if dataSourceName == "" {
+			t.Logf("Skipped %s, no data source name is given.", name)
+			continue
+		}
+
+		db := openDialectTestDB(t, name, dataSourceName)
+
+		dialect := GetDialect(db)
+		if dialect == nil || dialect.GetName() != name {
+			t.Fatalf("GetDialect should return %s.", name)
+		}
+
+		if err := db.Create(&DialectTestChild{ID: 1, DialectTestParentID: 1}).Error; err == nil {
+			t.Fatalf("%s: foreign keys should be checked after Initialize.", name)
+		}
+
+		err := Transaction(db, func(tx *gorm.DB) error {
+			if err := tx.Create(&DialectTestParent{ID: 1}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&DialectTestChild{ID: 1, DialectTestParentID: 1}).Error
+		})
+		if err != nil {
+			t.Fatalf("%s: Transaction should not fail. error: %v", name, err)
+		}
+
+		expectedError := errors.New("rollback")
+		err = Transaction(db, func(tx *gorm.DB) error {
+			if err := tx.Create(&DialectTestChild{ID: 2, DialectTestParentID: 1}).Error; err != nil {
+				return err
+			}
+			return expectedError
+		})
+		if err != expectedError {
+			t.Fatalf("%s: Transaction should return the error of the function. actual: %v", name, err)
+		}
+
+		count := 0
+		db.Model(&DialectTestChild{}).Count(&count)
+		if count != 1 {
+			t.Fatalf("%s: the failed function should be rolled back. count: %d", name, count)
+		}
+
+		err = Transaction(db, func(tx *gorm.DB) error {
+			return tx.Create(&DialectTestChild{ID: 3, DialectTestParentID: 3}).Error
+		})
+		if err == nil {
+			t.Fatalf("%s: foreign keys should be checked in transactions.", name)
+		}
+
+		db.DropTableIfExists(&DialectTestChild{}, &DialectTestParent{})
+		db.Close()
+	}
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type QueryParameterError struct {
+	Parameter string
+	Key       string
+	Message   string
+}
+
+func (this *QueryParameterError) Error() string {
+	return fmt.Sprintf("Invalid Parameter. %s", this.Message)
+}
+
+func (this *QueryParameterError) GetStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (this *QueryParameterError) GetErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"parameter": this.Parameter,
+		"key":       this.Key,
+	}
+}
+
+func newQueryParameterError(parameter string, key string, format string, args ...interface{}) *QueryParameterError {
+	return &QueryParameterError{
+		Parameter: parameter,
+		Key:       key,
+		Message:   fmt.Sprintf(format, args...),
+	}
+}
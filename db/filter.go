@@ -1,14 +1,43 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
+	"github.com/serenize/snaker"
 )
 
+const orGroupKey = "or"
+
+var filterKeyPattern = regexp.MustCompile(`^q((?:\[[^\[\]]+\])+)$`)
+
+var filterOperators = map[string]string{
+	"eq":    "= ?",
+	"ne":    "<> ?",
+	"gt":    "> ?",
+	"gte":   ">= ?",
+	"lt":    "< ?",
+	"lte":   "<= ?",
+	"like":  "LIKE ?",
+	"nlike": "NOT LIKE ?",
+	"in":    "IN (?)",
+	"nin":   "NOT IN (?)",
+	"null":  "",
+}
+
+type filterCondition struct {
+	group    string
+	field    string
+	operator string
+	value    string
+}
+
 func filterToMap(c *gin.Context, model interface{}) map[string]string {
 	var jsonTag, jsonKey string
 	filters := make(map[string]string)
@@ -28,26 +57,238 @@ func filterToMap(c *gin.Context, model interface{}) map[string]string {
 	return filters
 }
 
-func FilterFields(c *gin.Context, model interface{}, db *gorm.DB) *gorm.DB {
+func filterOperatorsToConditions(c *gin.Context) ([]*filterCondition, error) {
+	conditions := []*filterCondition{}
+	query := c.Request.URL.Query()
+
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		matches := filterKeyPattern.FindStringSubmatch(key)
+		if matches == nil {
+			continue
+		}
+
+		segments := strings.Split(strings.Trim(matches[1], "[]"), "][")
+
+		group := ""
+		if segments[0] == orGroupKey {
+			if len(segments) < 3 {
+				return nil, fmt.Errorf("Invalid Parameter. The filter '%s' needs a group name and a field.", key)
+			}
+			group = segments[1]
+			segments = segments[2:]
+		} else if len(segments) == 1 && !strings.Contains(segments[0], ".") {
+			// q[field]=a,b is handled by filterToMap.
+			continue
+		}
+
+		if len(segments) > 2 {
+			return nil, fmt.Errorf("Invalid Parameter. The filter '%s' is malformed.", key)
+		}
+
+		operator := "in"
+		if len(segments) == 2 {
+			operator = segments[1]
+		}
+		if _, ok := filterOperators[operator]; !ok {
+			return nil, fmt.Errorf("Invalid Parameter. The filter operator '%s' does not exist.", operator)
+		}
+
+		for _, value := range query[key] {
+			conditions = append(conditions, &filterCondition{
+				group:    group,
+				field:    segments[0],
+				operator: operator,
+				value:    value,
+			})
+		}
+	}
+
+	return conditions, nil
+}
+
+func structFieldsByJsonKey(scope *gorm.Scope) map[string]*gorm.StructField {
+	structFields := map[string]*gorm.StructField{}
+
+	for _, field := range scope.GetModelStruct().StructFields {
+		if field.IsIgnored {
+			continue
+		}
+
+		jsonKey := field.Name
+		if jsonTag := field.Struct.Tag.Get("json"); jsonTag != "" {
+			jsonKey = strings.Split(jsonTag, ",")[0]
+		}
+		if jsonKey == "-" {
+			continue
+		}
+
+		structFields[jsonKey] = field
+	}
+
+	return structFields
+}
+
+func lookupStructField(scope *gorm.Scope, key string) (*gorm.StructField, bool) {
+	if field, ok := structFieldsByJsonKey(scope)[key]; ok {
+		return field, true
+	}
+
+	// Accept the same notation as SetPreloads as well.
+	name := snaker.SnakeToCamel(key)
+	for _, field := range scope.GetModelStruct().StructFields {
+		if !field.IsIgnored && field.Name == name {
+			return field, true
+		}
+	}
+
+	return nil, false
+}
+
+func operatorToQuery(column string, condition *filterCondition) (string, []interface{}, error) {
+	switch condition.operator {
+	case "null":
+		switch condition.value {
+		case "true":
+			return fmt.Sprintf("%s IS NULL", column), []interface{}{}, nil
+		case "false":
+			return fmt.Sprintf("%s IS NOT NULL", column), []interface{}{}, nil
+		default:
+			return "", nil, fmt.Errorf("Invalid Parameter. The null filter accepts only true or false, but '%s'.", condition.value)
+		}
+	case "in", "nin":
+		return fmt.Sprintf("%s %s", column, filterOperators[condition.operator]), []interface{}{strings.Split(condition.value, ",")}, nil
+	default:
+		return fmt.Sprintf("%s %s", column, filterOperators[condition.operator]), []interface{}{condition.value}, nil
+	}
+}
+
+func pathToQuery(scope *gorm.Scope, path []string, condition *filterCondition) (string, []interface{}, error) {
+	field, ok := lookupStructField(scope, path[0])
+
+	if len(path) == 1 {
+		if !ok || !field.IsNormal {
+			return "", nil, newQueryParameterError("q", condition.field, "The filter field '%s' does not exist.", condition.field)
+		}
+
+		column := fmt.Sprintf("%s.%s", scope.QuotedTableName(), scope.Quote(field.DBName))
+		return operatorToQuery(column, condition)
+	}
+
+	if !ok || field.Relationship == nil {
+		return "", nil, newQueryParameterError("q", condition.field, "The filter association '%s' in '%s' does not exist.", path[0], condition.field)
+	}
+
+	relationship := field.Relationship
+	if len(relationship.ForeignDBNames) != 1 || len(relationship.AssociationForeignDBNames) != 1 {
+		return "", nil, newQueryParameterError("q", condition.field, "The filter association '%s' in '%s' is not supported.", path[0], condition.field)
+	}
+
+	associationType := field.Struct.Type
+	for associationType.Kind() == reflect.Slice || associationType.Kind() == reflect.Ptr {
+		associationType = associationType.Elem()
+	}
+	associationScope := scope.New(reflect.New(associationType).Interface())
+
+	query, args, err := pathToQuery(associationScope, path[1:], condition)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sourceColumn, associationColumn string
+	switch relationship.Kind {
+	case "has_many", "has_one":
+		sourceColumn = relationship.AssociationForeignDBNames[0]
+		associationColumn = relationship.ForeignDBNames[0]
+	case "belongs_to":
+		sourceColumn = relationship.ForeignDBNames[0]
+		associationColumn = relationship.AssociationForeignDBNames[0]
+	default:
+		return "", nil, newQueryParameterError("q", condition.field, "The filter association '%s' in '%s' is not supported.", path[0], condition.field)
+	}
+
+	subQuery := fmt.Sprintf("%s.%s IN (SELECT %s.%s FROM %s WHERE %s)",
+		scope.QuotedTableName(), scope.Quote(sourceColumn),
+		associationScope.QuotedTableName(), associationScope.Quote(associationColumn),
+		associationScope.QuotedTableName(), query)
+
+	return subQuery, args, nil
+}
+
+func conditionToQuery(scope *gorm.Scope, condition *filterCondition) (string, []interface{}, error) {
+	return pathToQuery(scope, strings.Split(condition.field, "."), condition)
+}
+
+func FilterFields(c *gin.Context, model interface{}, db *gorm.DB) (*gorm.DB, error) {
 	vs := reflect.ValueOf(model)
 	for vs.Kind() == reflect.Ptr {
 		vs = vs.Elem()
 	}
 	if !vs.IsValid() {
-		return nil
+		return nil, errors.New("Invalid model.")
 	}
 	if !vs.CanInterface() {
-		return nil
+		return nil, errors.New("Invalid model.")
 	}
 	value := vs.Interface()
 
+	scope := db.NewScope(model)
+
 	filters := filterToMap(c, value)
 
+	keys := []string{}
 	for k, v := range filters {
 		if v != "" {
-			db = db.Where(fmt.Sprintf("%s IN (?)", k), strings.Split(v, ","))
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	conditions := []*filterCondition{}
+	for _, k := range keys {
+		conditions = append(conditions, &filterCondition{
+			field:    k,
+			operator: "in",
+			value:    filters[k],
+		})
+	}
+
+	operatorConditions, err := filterOperatorsToConditions(c)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, operatorConditions...)
+
+	groups := []string{}
+	groupQueries := map[string][]string{}
+	groupArgs := map[string][]interface{}{}
+
+	for _, condition := range conditions {
+		query, args, err := conditionToQuery(scope, condition)
+		if err != nil {
+			return nil, err
+		}
+
+		if condition.group == "" {
+			db = db.Where(query, args...)
+			continue
+		}
+
+		if _, exists := groupQueries[condition.group]; !exists {
+			groups = append(groups, condition.group)
 		}
+		groupQueries[condition.group] = append(groupQueries[condition.group], "("+query+")")
+		groupArgs[condition.group] = append(groupArgs[condition.group], args...)
+	}
+
+	for _, group := range groups {
+		db = db.Where(strings.Join(groupQueries[group], " OR "), groupArgs[group]...)
 	}
 
-	return db
+	return db, nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 type User struct {
@@ -46,3 +47,96 @@ func TestFilterToMap(t *testing.T) {
 		t.Fatalf("filters[\"name\"] expected: `hoge,fuga`, actual: %s", value["id"])
 	}
 }
+
+func TestFilterOperatorsToConditions(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/?q[id]=1,5&q[id][gt]=10&q[name][like]=core-%25&q[or][g1][name][ne]=x&q[or][g1][engaged][null]=true", nil)
+	c := &gin.Context{
+		Request: req,
+	}
+	conditions, err := filterOperatorsToConditions(c)
+
+	if err != nil {
+		t.Fatalf("filterOperatorsToConditions should not fail. error: %v", err)
+	}
+
+	expected := []filterCondition{
+		{field: "id", operator: "gt", value: "10"},
+		{field: "name", operator: "like", value: "core-%"},
+		{group: "g1", field: "engaged", operator: "null", value: "true"},
+		{group: "g1", field: "name", operator: "ne", value: "x"},
+	}
+
+	if len(conditions) != len(expected) {
+		t.Fatalf("conditions should have %d items, actual: %d", len(expected), len(conditions))
+	}
+
+	for i, condition := range conditions {
+		if *condition != expected[i] {
+			t.Fatalf("conditions[%d] expected: %v, actual: %v", i, expected[i], *condition)
+		}
+	}
+}
+
+func TestFilterOperatorsToConditions_Invalid(t *testing.T) {
+	for _, query := range []string{"q[id][between]=1", "q[or][id]=1", "q[id][gt][lt]=1"} {
+		req, _ := http.NewRequest("GET", "/?"+query, nil)
+		c := &gin.Context{
+			Request: req,
+		}
+
+		if _, err := filterOperatorsToConditions(c); err == nil {
+			t.Fatalf("filterOperatorsToConditions should fail with `%s`.", query)
+		}
+	}
+}
+
+type Company struct {
+	ID        uint        `json:"id"`
+	Name      string      `json:"name"`
+	Employees []*Employee `json:"employees"`
+}
+
+type Employee struct {
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	CompanyID uint     `json:"company_id"`
+	Company   *Company `json:"company"`
+}
+
+func TestConditionToQuery_Association(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("gorm.Open should not fail. error: %v", err)
+	}
+	defer db.Close()
+
+	query, args, err := conditionToQuery(db.NewScope(&Company{}), &filterCondition{field: "employees.name", operator: "eq", value: "hoge"})
+	expected := `"companies"."id" IN (SELECT "employees"."company_id" FROM "employees" WHERE "employees"."name" = ?)`
+
+	if err != nil {
+		t.Fatalf("conditionToQuery should not fail. error: %v", err)
+	}
+
+	if query != expected {
+		t.Fatalf("query expected: `%s`, actual: `%s`", expected, query)
+	}
+
+	if len(args) != 1 || args[0] != "hoge" {
+		t.Fatalf("args expected: `[hoge]`, actual: %v", args)
+	}
+
+	query, _, err = conditionToQuery(db.NewScope(&Employee{}), &filterCondition{field: "Company.name", operator: "null", value: "false"})
+	expected = `"employees"."company_id" IN (SELECT "companies"."id" FROM "companies" WHERE "companies"."name" IS NOT NULL)`
+
+	if err != nil {
+		t.Fatalf("conditionToQuery should not fail. error: %v", err)
+	}
+
+	if query != expected {
+		t.Fatalf("query expected: `%s`, actual: `%s`", expected, query)
+	}
+
+	if _, _, err := conditionToQuery(db.NewScope(&Employee{}), &filterCondition{field: "name.id", operator: "eq", value: "1"}); err == nil {
+		t.Fatalf("conditionToQuery should fail with `name.id`.")
+	}
+}
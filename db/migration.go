@@ -0,0 +1,213 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+)
+
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primary_key"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+func checkMigrations(migrations []extension.Migration) error {
+	versions := map[int]string{}
+	for _, migration := range migrations {
+		if name, exists := versions[migration.GetVersion()]; exists {
+			return fmt.Errorf("The migration version %d is registered twice by '%s' and '%s'.", migration.GetVersion(), name, migration.GetName())
+		}
+		versions[migration.GetVersion()] = migration.GetName()
+	}
+	return nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]*SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+
+	schemaMigrations := []*SchemaMigration{}
+	if err := db.Order("version asc").Find(&schemaMigrations).Error; err != nil {
+		return nil, err
+	}
+
+	result := map[int]*SchemaMigration{}
+	for _, schemaMigration := range schemaMigrations {
+		result[schemaMigration.Version] = schemaMigration
+	}
+	return result, nil
+}
+
+func markMigrationApplied(db *gorm.DB, migration extension.Migration) error {
+	schemaMigration := &SchemaMigration{
+		Version:   migration.GetVersion(),
+		Name:      migration.GetName(),
+		AppliedAt: time.Now(),
+	}
+	return db.Create(schemaMigration).Error
+}
+
+func migrateUp(db *gorm.DB, migrations []extension.Migration) ([]extension.Migration, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []extension.Migration{}
+	for _, migration := range migrations {
+		if _, exists := applied[migration.GetVersion()]; exists {
+			continue
+		}
+
+		err := Transaction(db, func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return markMigrationApplied(tx, migration)
+		})
+		if err != nil {
+			return result, fmt.Errorf("The migration %d '%s' failed: %v", migration.GetVersion(), migration.GetName(), err)
+		}
+
+		result = append(result, migration)
+	}
+
+	return result, nil
+}
+
+func migrateDown(db *gorm.DB, migrations []extension.Migration, steps int) ([]extension.Migration, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	registered := map[int]extension.Migration{}
+	for _, migration := range migrations {
+		registered[migration.GetVersion()] = migration
+	}
+
+	versions := []int{}
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	result := []extension.Migration{}
+	for _, version := range versions {
+		if len(result) >= steps {
+			break
+		}
+
+		migration, exists := registered[version]
+		if !exists {
+			return result, fmt.Errorf("The migration %d '%s' is applied, but not registered.", version, applied[version].Name)
+		}
+
+		err := Transaction(db, func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(applied[version]).Error
+		})
+		if err != nil {
+			return result, fmt.Errorf("The migration %d '%s' failed: %v", migration.GetVersion(), migration.GetName(), err)
+		}
+
+		result = append(result, migration)
+	}
+
+	return result, nil
+}
+
+func migrationStatus(db *gorm.DB, migrations []extension.Migration) ([]*MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*MigrationStatus{}
+	for _, migration := range migrations {
+		status := &MigrationStatus{
+			Version: migration.GetVersion(),
+			Name:    migration.GetName(),
+		}
+		if schemaMigration, exists := applied[migration.GetVersion()]; exists {
+			status.Applied = true
+			status.AppliedAt = &schemaMigration.AppliedAt
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+func isFreshDatabase(db *gorm.DB, models []interface{}) bool {
+	if db.HasTable(&SchemaMigration{}) {
+		return false
+	}
+	for _, model := range models {
+		if db.HasTable(model) {
+			return false
+		}
+	}
+	return true
+}
+
+func migrateSchema(db *gorm.DB, models []interface{}, migrations []extension.Migration) error {
+	if isFreshDatabase(db, models) {
+		// The models already describe the latest schema, so every migration is recorded as applied.
+		if err := db.AutoMigrate(models...).Error; err != nil {
+			return err
+		}
+		if err := checkMigrations(migrations); err != nil {
+			return err
+		}
+		if _, err := appliedMigrations(db); err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if err := markMigrationApplied(db, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, err := migrateUp(db, migrations); err != nil {
+		return err
+	}
+
+	return db.AutoMigrate(models...).Error
+}
+
+func MigrateUp(db *gorm.DB) ([]extension.Migration, error) {
+	return migrateUp(db, extension.GetMigrations())
+}
+
+func MigrateDown(db *gorm.DB, steps int) ([]extension.Migration, error) {
+	return migrateDown(db, extension.GetMigrations(), steps)
+}
+
+func GetMigrationStatus(db *gorm.DB) ([]*MigrationStatus, error) {
+	return migrationStatus(db, extension.GetMigrations())
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+)
+
+type MigrationTestModel struct {
+	ID   int    `gorm:"primary_key"`
+	Name string `json:"name"`
+}
+
+type testMigration struct {
+	version int
+	up      func(*gorm.DB) error
+	down    func(*gorm.DB) error
+}
+
+func (this *testMigration) GetVersion() int {
+	return this.version
+}
+
+func (this *testMigration) GetName() string {
+	return "test"
+}
+
+func (this *testMigration) Up(db *gorm.DB) error {
+	return this.up(db)
+}
+
+func (this *testMigration) Down(db *gorm.DB) error {
+	return this.down(db)
+}
+
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("gorm.Open should not fail. error: %v", err)
+	}
+	// Every connection to :memory: is a different database.
+	db.DB().SetMaxOpenConns(1)
+	return db
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	db := openMigrationTestDB(t)
+	defer db.Close()
+
+	db.Exec("create table migration_test_models (id integer primary key, title varchar(255));")
+
+	migrations := []extension.Migration{
+		&testMigration{
+			version: 1,
+			up: func(db *gorm.DB) error {
+				return db.Exec("alter table migration_test_models rename to migration_test_models_old;").Error
+			},
+			down: func(db *gorm.DB) error {
+				return db.Exec("alter table migration_test_models_old rename to migration_test_models;").Error
+			},
+		},
+		&testMigration{
+			version: 2,
+			up: func(db *gorm.DB) error {
+				return db.CreateTable(&MigrationTestModel{}).Error
+			},
+			down: func(db *gorm.DB) error {
+				return db.DropTable(&MigrationTestModel{}).Error
+			},
+		},
+	}
+
+	applied, err := migrateUp(db, migrations)
+	if err != nil {
+		t.Fatalf("migrateUp should not fail. error: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("migrateUp should apply 2 migrations, actual: %d", len(applied))
+	}
+	if !db.Dialect().HasColumn("migration_test_models", "name") {
+		t.Fatalf("migration_test_models should have `name` column.")
+	}
+
+	applied, err = migrateUp(db, migrations)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("migrateUp should apply nothing twice. applied: %d, error: %v", len(applied), err)
+	}
+
+	reverted, err := migrateDown(db, migrations, 1)
+	if err != nil {
+		t.Fatalf("migrateDown should not fail. error: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].GetVersion() != 2 {
+		t.Fatalf("migrateDown should revert the migration 2.")
+	}
+
+	statuses, err := migrationStatus(db, migrations)
+	if err != nil {
+		t.Fatalf("migrationStatus should not fail. error: %v", err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("Only the migration 1 should be applied.")
+	}
+}
+
+func TestMigrateUp_Failure(t *testing.T) {
+	db := openMigrationTestDB(t)
+	defer db.Close()
+
+	migrations := []extension.Migration{
+		&testMigration{
+			version: 1,
+			up: func(db *gorm.DB) error {
+				return errors.New("failure")
+			},
+		},
+	}
+
+	if _, err := migrateUp(db, migrations); err == nil {
+		t.Fatalf("migrateUp should fail.")
+	}
+
+	statuses, _ := migrationStatus(db, migrations)
+	if statuses[0].Applied {
+		t.Fatalf("The failed migration should not be recorded.")
+	}
+}
+
+func TestMigrateSchema_Fresh(t *testing.T) {
+	db := openMigrationTestDB(t)
+	defer db.Close()
+
+	migrations := []extension.Migration{
+		&testMigration{
+			version: 1,
+			up: func(db *gorm.DB) error {
+				return errors.New("should not be called")
+			},
+		},
+	}
+
+	if err := migrateSchema(db, []interface{}{&MigrationTestModel{}}, migrations); err != nil {
+		t.Fatalf("migrateSchema should not fail. error: %v", err)
+	}
+
+	if !db.HasTable(&MigrationTestModel{}) {
+		t.Fatalf("migration_test_models should be created.")
+	}
+
+	statuses, _ := migrationStatus(db, migrations)
+	if !statuses[0].Applied {
+		t.Fatalf("The migrations should be recorded as applied on a fresh database.")
+	}
+}
+
+func TestCheckMigrations_Duplicated(t *testing.T) {
+	migrations := []extension.Migration{
+		&testMigration{version: 1},
+		&testMigration{version: 1},
+	}
+
+	if err := checkMigrations(migrations); err == nil {
+		t.Fatalf("checkMigrations should fail with duplicated versions.")
+	}
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+type Pagination struct {
+	Limit  int
+	Offset int
+	After  string
+}
+
+func parsePaginationValue(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		return 0, fmt.Errorf("Invalid Parameter. %s must be a non-negative integer, but '%s'.", key, value)
+	}
+
+	return result, nil
+}
+
+func ParsePagination(c *gin.Context) (*Pagination, error) {
+	limit, err := parsePaginationValue(c, "limit")
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := parsePaginationValue(c, "offset")
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 && limit == 0 {
+		return nil, errors.New("Invalid Parameter. offset cannot be used without limit.")
+	}
+
+	after := c.Query("after")
+	if after != "" {
+		if _, err := strconv.Atoi(after); err != nil {
+			return nil, fmt.Errorf("Invalid Parameter. after must be an id, but '%s'.", after)
+		}
+		if c.Query("sort") != "" {
+			return nil, errors.New("Invalid Parameter. after cannot be combined with sort.")
+		}
+	}
+
+	pagination := &Pagination{
+		Limit:  limit,
+		Offset: offset,
+		After:  after,
+	}
+
+	return pagination, nil
+}
+
+func CountRecords(model interface{}, db *gorm.DB) (int, error) {
+	total := 0
+
+	if err := db.Model(model).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func PageRecords(pagination *Pagination, model interface{}, db *gorm.DB) *gorm.DB {
+	if pagination.After != "" {
+		scope := db.NewScope(model)
+		primaryKey := fmt.Sprintf("%s.%s", scope.QuotedTableName(), scope.Quote(scope.PrimaryKey()))
+		db = db.Where(fmt.Sprintf("%s > ?", primaryKey), pagination.After).Order(primaryKey + " asc")
+	}
+
+	if pagination.Limit > 0 {
+		db = db.Limit(pagination.Limit)
+	}
+
+	if pagination.Offset > 0 {
+		db = db.Offset(pagination.Offset)
+	}
+
+	return db
+}
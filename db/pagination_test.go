@@ -0,0 +1,61 @@
+package db
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePagination(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/?limit=10&offset=20", nil)
+	c := &gin.Context{
+		Request: req,
+	}
+	pagination, err := ParsePagination(c)
+
+	if err != nil {
+		t.Fatalf("ParsePagination should not fail. error: %v", err)
+	}
+
+	if pagination.Limit != 10 {
+		t.Fatalf("pagination.Limit expected: `10`, actual: %d", pagination.Limit)
+	}
+
+	if pagination.Offset != 20 {
+		t.Fatalf("pagination.Offset expected: `20`, actual: %d", pagination.Offset)
+	}
+
+	if pagination.After != "" {
+		t.Fatalf("pagination.After expected: ``, actual: %s", pagination.After)
+	}
+}
+
+func TestParsePagination_After(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/?limit=10&after=5", nil)
+	c := &gin.Context{
+		Request: req,
+	}
+	pagination, err := ParsePagination(c)
+
+	if err != nil {
+		t.Fatalf("ParsePagination should not fail. error: %v", err)
+	}
+
+	if pagination.After != "5" {
+		t.Fatalf("pagination.After expected: `5`, actual: %s", pagination.After)
+	}
+}
+
+func TestParsePagination_Invalid(t *testing.T) {
+	for _, query := range []string{"limit=-1", "limit=a", "offset=10", "after=a", "after=1&sort=name"} {
+		req, _ := http.NewRequest("GET", "/?"+query, nil)
+		c := &gin.Context{
+			Request: req,
+		}
+
+		if _, err := ParsePagination(c); err == nil {
+			t.Fatalf("ParsePagination should fail with `%s`.", query)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/jinzhu/gorm"
@@ -14,14 +16,73 @@ func convertPrefixToQuery(sort string) string {
 	}
 }
 
-func SortRecords(sorts string, db *gorm.DB) *gorm.DB {
+func sortPathToColumn(scope *gorm.Scope, path []string, key string) (string, error) {
+	field, ok := lookupStructField(scope, path[0])
+
+	if len(path) == 1 {
+		if !ok || !field.IsNormal {
+			return "", newQueryParameterError("sort", key, "The sort key '%s' does not exist.", key)
+		}
+
+		return fmt.Sprintf("%s.%s", scope.QuotedTableName(), scope.Quote(field.DBName)), nil
+	}
+
+	if !ok || field.Relationship == nil {
+		return "", newQueryParameterError("sort", key, "The sort association '%s' in '%s' does not exist.", path[0], key)
+	}
+
+	relationship := field.Relationship
+	if len(relationship.ForeignDBNames) != 1 || len(relationship.AssociationForeignDBNames) != 1 {
+		return "", newQueryParameterError("sort", key, "The sort association '%s' in '%s' is not supported.", path[0], key)
+	}
+
+	associationType := field.Struct.Type
+	for associationType.Kind() == reflect.Slice || associationType.Kind() == reflect.Ptr {
+		associationType = associationType.Elem()
+	}
+	associationScope := scope.New(reflect.New(associationType).Interface())
+
+	column, err := sortPathToColumn(associationScope, path[1:], key)
+	if err != nil {
+		return "", err
+	}
+
+	switch relationship.Kind {
+	case "belongs_to":
+		return fmt.Sprintf("(SELECT %s FROM %s WHERE %s.%s = %s.%s)",
+			column, associationScope.QuotedTableName(),
+			associationScope.QuotedTableName(), associationScope.Quote(relationship.AssociationForeignDBNames[0]),
+			scope.QuotedTableName(), scope.Quote(relationship.ForeignDBNames[0])), nil
+	case "has_one":
+		return fmt.Sprintf("(SELECT %s FROM %s WHERE %s.%s = %s.%s LIMIT 1)",
+			column, associationScope.QuotedTableName(),
+			associationScope.QuotedTableName(), associationScope.Quote(relationship.ForeignDBNames[0]),
+			scope.QuotedTableName(), scope.Quote(relationship.AssociationForeignDBNames[0])), nil
+	default:
+		return "", newQueryParameterError("sort", key, "The sort association '%s' in '%s' has many records, and cannot be used as a sort key.", path[0], key)
+	}
+}
+
+func SortRecords(sorts string, model interface{}, db *gorm.DB) (*gorm.DB, error) {
 	if sorts == "" {
-		return db
+		return db, nil
 	}
 
+	scope := db.NewScope(model)
+
 	for _, sort := range strings.Split(sorts, ",") {
-		db = db.Order(convertPrefixToQuery(sort))
+		query := strings.Fields(convertPrefixToQuery(sort))
+		if len(query) != 2 {
+			return nil, newQueryParameterError("sort", sort, "The sort key '%s' is malformed.", sort)
+		}
+
+		column, err := sortPathToColumn(scope, strings.Split(query[0], "."), query[0])
+		if err != nil {
+			return nil, err
+		}
+
+		db = db.Order(fmt.Sprintf("%s %s", column, query[1]))
 	}
 
-	return db
+	return db, nil
 }
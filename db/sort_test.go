@@ -1,6 +1,11 @@
 package db
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
 
 func TestConvertPrefixToQueryPlus(t *testing.T) {
 	value := convertPrefixToQuery("id")
@@ -23,3 +28,53 @@ func TestConvertPrefixToQueryMinus(t *testing.T) {
 		t.Fatalf("Expected: `id desc`, actual: %s", value)
 	}
 }
+
+func TestSortPathToColumn(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("gorm.Open should not fail. error: %v", err)
+	}
+	defer db.Close()
+
+	column, err := sortPathToColumn(db.NewScope(&Employee{}), []string{"name"}, "name")
+	expected := `"employees"."name"`
+
+	if err != nil {
+		t.Fatalf("sortPathToColumn should not fail. error: %v", err)
+	}
+
+	if column != expected {
+		t.Fatalf("Expected: `%s`, actual: `%s`", expected, column)
+	}
+
+	column, err = sortPathToColumn(db.NewScope(&Employee{}), []string{"company", "name"}, "company.name")
+	expected = `(SELECT "companies"."name" FROM "companies" WHERE "companies"."id" = "employees"."company_id")`
+
+	if err != nil {
+		t.Fatalf("sortPathToColumn should not fail. error: %v", err)
+	}
+
+	if column != expected {
+		t.Fatalf("Expected: `%s`, actual: `%s`", expected, column)
+	}
+}
+
+func TestSortPathToColumn_Invalid(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("gorm.Open should not fail. error: %v", err)
+	}
+	defer db.Close()
+
+	for _, key := range []string{"nme", "employees.name", "company.unexisted_field", "id;drop table companies"} {
+		_, err := sortPathToColumn(db.NewScope(&Company{}), strings.Split(key, "."), key)
+
+		if err == nil {
+			t.Fatalf("sortPathToColumn should fail with `%s`.", key)
+		}
+
+		if parameterError, ok := err.(*QueryParameterError); !ok || parameterError.Key != key {
+			t.Fatalf("sortPathToColumn should return QueryParameterError for `%s`. actual: %v", key, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	"reflect"
+	"sort"
 	"text/template"
 )
 
@@ -48,22 +49,85 @@ type RouterInitializer interface {
 }
 
 type DesignAccessor interface {
+	GetDesignKey() string
+	// GetDesignDependencies maps fields to the design keys they refer to.
+	GetDesignDependencies() map[string]string
 	ExtractFromDesign(*gorm.DB) (string, interface{}, error)
 	DeleteFromDesign(*gorm.DB) error
 	LoadToDesign(*gorm.DB, interface{}) error
+	MergeToDesign(*gorm.DB, interface{}) error
 }
 
 type TemplateParameterGenerator interface {
 	GenerateTemplateParameter(*gorm.DB) (string, interface{}, error)
 }
 
+type Migration interface {
+	GetVersion() int
+	GetName() string
+	Up(*gorm.DB) error
+	Down(*gorm.DB) error
+}
+
+type DesignUpgrade interface {
+	GetDesignKey() string
+	// GetVersion returns the schema version which Upgrade converts from, to the next one.
+	GetVersion() int
+	Upgrade(interface{}) (interface{}, error)
+}
+
+// ModelChangedHook is notified after models are created, updated or deleted, or the design is imported or deleted.
+type ModelChangedHook interface {
+	ModelChanged()
+}
+
+// StatusCodeError is an error which decides the status code of the error response,
+// and adds GetErrorDetails to it next to the message.
+type StatusCodeError interface {
+	error
+	GetStatusCode() int
+	GetErrorDetails() map[string]interface{}
+}
+
+type migrationsByVersion []Migration
+
+func (this migrationsByVersion) Len() int {
+	return len(this)
+}
+
+func (this migrationsByVersion) Less(i, j int) bool {
+	return this[i].GetVersion() < this[j].GetVersion()
+}
+
+func (this migrationsByVersion) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
+
+type designUpgradesByVersion []DesignUpgrade
+
+func (this designUpgradesByVersion) Len() int {
+	return len(this)
+}
+
+func (this designUpgradesByVersion) Less(i, j int) bool {
+	return this[i].GetVersion() < this[j].GetVersion()
+}
+
+func (this designUpgradesByVersion) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
+
 var typeMap = map[string]reflect.Type{}
 var modelMap = map[reflect.Type]interface{}{}
+var models = []interface{}{}
 var controllers = []Controller{}
 var routerInitializers = []RouterInitializer{}
 var designAccessors = []DesignAccessor{}
 var templateParameterGenerators = []TemplateParameterGenerator{}
 var templateFuncMaps = []template.FuncMap{}
+var migrations = []Migration{}
+var designUpgrades = []DesignUpgrade{}
+var modelChangedHooks = []ModelChangedHook{}
 
 func GetMethodName(method int) string {
 	return methodNameMap[method]
@@ -71,15 +135,17 @@ func GetMethodName(method int) string {
 
 func RegisterModelType(model interface{}) {
 	reflectType := reflect.TypeOf(model)
+	if _, exists := modelMap[reflectType]; exists {
+		return
+	}
 	typeMap[reflectType.String()] = reflectType
 	modelMap[reflectType] = reflect.New(reflectType).Elem().Interface()
+	models = append(models, modelMap[reflectType])
 }
 
 func GetModels() []interface{} {
 	result := []interface{}{}
-	for _, model := range modelMap {
-		result = append(result, model)
-	}
+	result = append(result, models...)
 	return result
 }
 
@@ -140,3 +206,49 @@ func GetTemplateFuncMaps() []template.FuncMap {
 	result = append(result, templateFuncMaps...)
 	return result
 }
+
+func RegisterMigration(migration Migration) {
+	migrations = append(migrations, migration)
+}
+
+func GetMigrations() []Migration {
+	result := []Migration{}
+	result = append(result, migrations...)
+	sort.Stable(migrationsByVersion(result))
+	return result
+}
+
+func RegisterDesignUpgrade(designUpgrade DesignUpgrade) {
+	designUpgrades = append(designUpgrades, designUpgrade)
+}
+
+func GetDesignUpgrades(key string) []DesignUpgrade {
+	result := []DesignUpgrade{}
+	for _, designUpgrade := range designUpgrades {
+		if designUpgrade.GetDesignKey() == key {
+			result = append(result, designUpgrade)
+		}
+	}
+	sort.Stable(designUpgradesByVersion(result))
+	return result
+}
+
+func GetDesignSchemaVersion(key string) int {
+	version := 1
+	for _, designUpgrade := range designUpgrades {
+		if designUpgrade.GetDesignKey() == key && designUpgrade.GetVersion() >= version {
+			version = designUpgrade.GetVersion() + 1
+		}
+	}
+	return version
+}
+
+func RegisterModelChangedHook(modelChangedHook ModelChangedHook) {
+	modelChangedHooks = append(modelChangedHooks, modelChangedHook)
+}
+
+func NotifyModelChanged() {
+	for _, modelChangedHook := range modelChangedHooks {
+		modelChangedHook.ModelChanged()
+	}
+}
@@ -0,0 +1,32 @@
+package helper
+
+const (
+	ArchiveTar = "tar"
+	ArchiveZip = "zip"
+)
+
+var archiveContentTypes = map[string]string{
+	ArchiveTar: "application/gzip",
+	ArchiveZip: "application/zip",
+}
+
+var archiveFileExtensions = map[string]string{
+	ArchiveTar: "tar.gz",
+	ArchiveZip: "zip",
+}
+
+// ArchiveContentType returns the content type of the archive kind, defaulting to the gzipped tar.
+func ArchiveContentType(archive string) string {
+	if contentType, exists := archiveContentTypes[archive]; exists {
+		return contentType
+	}
+	return archiveContentTypes[ArchiveTar]
+}
+
+// ArchiveFileExtension returns the file extension of the archive kind, defaulting to the gzipped tar.
+func ArchiveFileExtension(archive string) string {
+	if extension, exists := archiveFileExtensions[archive]; exists {
+		return extension
+	}
+	return archiveFileExtensions[ArchiveTar]
+}
@@ -0,0 +1,183 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+func decodeOrderedJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			result := yaml.MapSlice{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, yaml.MapItem{Key: key, Value: value})
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return result, nil
+		case '[':
+			result := []interface{}{}
+			for decoder.More() {
+				value, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, value)
+			}
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return result, nil
+		default:
+			return nil, fmt.Errorf("Unexpected delimiter %v.", token)
+		}
+	case json.Number:
+		if value, err := token.Int64(); err == nil {
+			return value, nil
+		}
+		return token.Float64()
+	default:
+		return token, nil
+	}
+}
+
+// Values are converted through JSON, so that the json tags of the models are used in every format.
+func toOrderedValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeOrderedJSON(decoder)
+}
+
+func toStringKeys(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range value {
+			converted, err := toStringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(key)] = converted
+		}
+		return result, nil
+	case yaml.MapSlice:
+		result := map[string]interface{}{}
+		for _, item := range value {
+			converted, err := toStringKeys(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(item.Key)] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			converted, err := toStringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+func fromGenericValue(value interface{}, container interface{}) error {
+	value, err := toStringKeys(value)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, container)
+}
+
+func MarshalYAML(value interface{}) ([]byte, error) {
+	orderedValue, err := toOrderedValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(orderedValue)
+}
+
+func UnmarshalYAML(data []byte, container interface{}) error {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	return fromGenericValue(value, container)
+}
+
+// TOMLItemsKey is the key of the table which holds values other than objects, like resource lists, in TOML.
+const TOMLItemsKey = "items"
+
+func MarshalTOML(value interface{}) ([]byte, error) {
+	orderedValue, err := toOrderedValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := orderedValue.(yaml.MapSlice); !ok {
+		orderedValue = yaml.MapSlice{{Key: TOMLItemsKey, Value: orderedValue}}
+	}
+
+	tomlValue, err := toStringKeys(orderedValue)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := toml.NewEncoder(buffer).Encode(tomlValue); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func UnmarshalTOML(data []byte, container interface{}) error {
+	value := map[string]interface{}{}
+	if _, err := toml.Decode(string(data), &value); err != nil {
+		return err
+	}
+
+	if reflect.Indirect(reflect.ValueOf(container)).Kind() == reflect.Slice {
+		return fromGenericValue(value[TOMLItemsKey], container)
+	}
+
+	return fromGenericValue(value, container)
+}
@@ -0,0 +1,99 @@
+package helper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type FormatTestParameter struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+type FormatTestTemplate struct {
+	ID              int                    `json:"id"`
+	Name            string                 `json:"name"`
+	TemplateContent string                 `json:"template_content"`
+	Parameters      []*FormatTestParameter `json:"parameters"`
+}
+
+func newFormatTestTemplate() *FormatTestTemplate {
+	return &FormatTestTemplate{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "{{ .Name }}",
+		Parameters: []*FormatTestParameter{
+			{
+				ID:    1,
+				Name:  "parameter1",
+				Value: "value1",
+			},
+			{
+				ID:   2,
+				Name: "parameter2",
+			},
+		},
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	data, err := MarshalYAML(newFormatTestTemplate())
+	if err != nil {
+		t.Fatalf("MarshalYAML should not fail. error: %v", err)
+	}
+
+	expected := `id: 1
+name: test1
+template_content: '{{ .Name }}'
+parameters:
+- id: 1
+  name: parameter1
+  value: value1
+- id: 2
+  name: parameter2
+`
+	if string(data) != expected {
+		t.Fatalf("MarshalYAML should keep the field order.\nexpected:\n%s\nactual:\n%s", expected, string(data))
+	}
+
+	result := &FormatTestTemplate{}
+	if err := UnmarshalYAML(data, result); err != nil {
+		t.Fatalf("UnmarshalYAML should not fail. error: %v", err)
+	}
+	if !reflect.DeepEqual(result, newFormatTestTemplate()) {
+		t.Fatalf("UnmarshalYAML should restore the value. actual: %v", result)
+	}
+}
+
+func TestMarshalTOML(t *testing.T) {
+	data, err := MarshalTOML(newFormatTestTemplate())
+	if err != nil {
+		t.Fatalf("MarshalTOML should not fail. error: %v", err)
+	}
+
+	result := &FormatTestTemplate{}
+	if err := UnmarshalTOML(data, result); err != nil {
+		t.Fatalf("UnmarshalTOML should not fail. error: %v\n%s", err, string(data))
+	}
+	if !reflect.DeepEqual(result, newFormatTestTemplate()) {
+		t.Fatalf("UnmarshalTOML should restore the value. actual: %v", result)
+	}
+
+	data, err = MarshalTOML([]*FormatTestTemplate{newFormatTestTemplate()})
+	if err != nil {
+		t.Fatalf("MarshalTOML should not fail with a list. error: %v", err)
+	}
+	if !strings.Contains(string(data), "[["+TOMLItemsKey+"]]") {
+		t.Fatalf("MarshalTOML should put a list under %s. actual: %s", TOMLItemsKey, string(data))
+	}
+
+	results := []*FormatTestTemplate{}
+	if err := UnmarshalTOML(data, &results); err != nil {
+		t.Fatalf("UnmarshalTOML should not fail with a list. error: %v\n%s", err, string(data))
+	}
+	if !reflect.DeepEqual(results, []*FormatTestTemplate{newFormatTestTemplate()}) {
+		t.Fatalf("UnmarshalTOML should restore the list. actual: %v", results)
+	}
+}
@@ -1,13 +1,21 @@
 package integration
 
 import (
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/integration/fixtures"
+	"github.com/qb0C80aE/clay/logics"
 	"github.com/qb0C80aE/clay/models"
 	"net/http"
+	"os"
 	"testing"
 )
 
 // +build integration
 
+func init() {
+	extension.RegisterDesignUpgrade(&fixtures.DesignUpgrade{})
+}
+
 func TestGetDesign_Empty(t *testing.T) {
 	server := SetupServer()
 	defer server.Close()
@@ -138,3 +146,539 @@ func TestDeleteDesign(t *testing.T) {
 	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
 	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDeleteDesign_2.json"), &models.Design{})
 }
+
+type designSnapshotWithoutTimestamp struct {
+	ID     int            `json:"id"`
+	Name   string         `json:"name"`
+	Design *models.Design `json:"design,omitempty"`
+}
+
+func TestDesignSnapshots(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template1 := &models.Template{
+		Name:            "test1",
+		TemplateContent: "TestTemplate1",
+		TemplateExternalParameters: []*models.TemplateExternalParameter{
+			{
+				Name:  "testParameter1",
+				Value: "TestParameter1",
+			},
+		},
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template1)
+
+	snapshot := &models.DesignSnapshot{
+		Name: "snapshot1",
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "designs", nil), snapshot)
+	CheckResponseJson(t, code, http.StatusCreated, responseText, LoadExpectation(t, "design/TestDesignSnapshots_1.json"), &designSnapshotWithoutTimestamp{})
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test2",
+					TemplateContent: "TestTemplate2",
+				},
+			},
+		},
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "designs", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_2.json"), []*designSnapshotWithoutTimestamp{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "1", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_1.json"), &designSnapshotWithoutTimestamp{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "1/diff/present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_3.json"), &models.DesignDiff{})
+
+	parameters := map[string]string{
+		"from": "1",
+	}
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_4.json"), &models.Design{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_4.json"), &models.Design{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "1/diff/present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesignSnapshots_5.json"), &models.DesignDiff{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "2", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "design/TestDesignSnapshots_6.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusNoContent, responseText, []byte{})
+
+	responseText, code = Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "1", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "design/TestDesignSnapshots_6.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "validate", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "design/TestDesignSnapshots_6.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "designs", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, EmptyArrayString, []*designSnapshotWithoutTimestamp{})
+}
+
+func TestDiffDesign(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1",
+				},
+				{
+					ID:              2,
+					Name:            "test2",
+					TemplateContent: "TestTemplate2",
+				},
+			},
+		},
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	uploadedDesign := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []*models.TemplateExternalParameter{
+				{
+					ID:         1,
+					TemplateID: 1,
+					Name:       "testParameter11",
+					Value:      "TestParameter11",
+				},
+			},
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1Modified",
+				},
+				{
+					ID:              3,
+					Name:            "test3",
+					TemplateContent: "TestTemplate3",
+				},
+			},
+		},
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "present/diff", nil), uploadedDesign)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDiffDesign_1.json"), &models.DesignDiff{})
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "1/diff", nil), uploadedDesign)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "design/TestDiffDesign_2.json"), &ErrorResponseText{})
+}
+
+func TestUpdateDesign_Modes(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []*models.TemplateExternalParameter{
+				{
+					ID:         1,
+					TemplateID: 1,
+					Name:       "testParameter11",
+					Value:      "TestParameter11",
+				},
+				{
+					ID:         2,
+					TemplateID: 1,
+					Name:       "testParameter12",
+					Value:      "TestParameter12",
+				},
+			},
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1",
+				},
+				{
+					ID:              2,
+					Name:            "test2",
+					TemplateContent: "TestTemplate2",
+				},
+			},
+		},
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	mergedDesign := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              2,
+					Name:            "test2",
+					TemplateContent: "TestTemplate2Modified",
+				},
+				{
+					ID:              3,
+					Name:            "test3",
+					TemplateContent: "TestTemplate3",
+				},
+			},
+		},
+	}
+
+	parameters := map[string]string{
+		"mode": "merge",
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", parameters), mergedDesign)
+
+	responseText, code := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestUpdateDesign_Modes_1.json"), &models.Design{})
+
+	patchedDesign := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1Modified",
+				},
+			},
+		},
+	}
+
+	parameters = map[string]string{
+		"mode": "patch",
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", parameters), patchedDesign)
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestUpdateDesign_Modes_2.json"), &models.Design{})
+
+	parameters = map[string]string{
+		"mode": "unknown",
+	}
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", parameters), patchedDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestUpdateDesign_Modes_3.json"), &ErrorResponseText{})
+}
+
+type designValidationErrorResponseText struct {
+	Error    string                  `json:"error"`
+	Problems []*logics.DesignProblem `json:"problems"`
+}
+
+func TestUpdateDesign_DanglingReference(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []*models.TemplateExternalParameter{
+				{
+					ID:         1,
+					TemplateID: 1,
+					Name:       "testParameter11",
+					Value:      "TestParameter11",
+				},
+				{
+					ID:         2,
+					TemplateID: 3,
+					Name:       "testParameter32",
+					Value:      "TestParameter32",
+				},
+			},
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1",
+				},
+			},
+		},
+	}
+
+	responseText, code := Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestUpdateDesign_DanglingReference_1.json"), &designValidationErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestGetDesign_Empty_1.json"), &models.Design{})
+
+	patchedDesign := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              2,
+					Name:            "test2",
+					TemplateContent: "TestTemplate2",
+				},
+			},
+		},
+	}
+
+	design.Content["template_external_parameters"] = design.Content["template_external_parameters"].([]*models.TemplateExternalParameter)[:1]
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	parameters := map[string]string{
+		"mode": "patch",
+	}
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", parameters), patchedDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestUpdateDesign_DanglingReference_2.json"), &designValidationErrorResponseText{})
+}
+
+type designProblemWithoutMessage struct {
+	Kind  string      `json:"kind"`
+	Key   string      `json:"key"`
+	ID    interface{} `json:"id,omitempty"`
+	Field string      `json:"field,omitempty"`
+}
+
+type designValidationResultWithoutMessage struct {
+	Valid    bool                           `json:"valid"`
+	Problems []*designProblemWithoutMessage `json:"problems"`
+}
+
+func TestValidateDesign(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []*models.TemplateExternalParameter{
+				{
+					ID:         1,
+					TemplateID: 1,
+					Name:       "testParameter11",
+					Value:      "TestParameter11",
+				},
+			},
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1",
+				},
+			},
+		},
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "validate", nil), design)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestValidateDesign_1.json"), &designValidationResultWithoutMessage{})
+
+	brokenDesign := map[string]interface{}{
+		"content": map[string]interface{}{
+			"template_external_parameters": []interface{}{
+				map[string]interface{}{
+					"id":          1,
+					"template_id": 3,
+					"name":        "testParameter31",
+				},
+				map[string]interface{}{
+					"id":          2,
+					"template_id": 2,
+					"name":        "testParameter22",
+				},
+			},
+			"templates": []interface{}{
+				map[string]interface{}{
+					"id":   1,
+					"name": "test1",
+				},
+				map[string]interface{}{
+					"id":   1,
+					"name": "test1",
+				},
+				map[string]interface{}{
+					"id":   2,
+					"name": 2,
+				},
+			},
+			"unknowns": []interface{}{},
+		},
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "validate", nil), brokenDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestValidateDesign_2.json"), &designValidationResultWithoutMessage{})
+
+	nonScalarIDDesign := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []interface{}{
+				map[string]interface{}{
+					"id":          []interface{}{1},
+					"template_id": 9,
+					"name":        "testParameter91",
+				},
+			},
+		},
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "validate", nil), nonScalarIDDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestValidateDesign_3.json"), &designValidationResultWithoutMessage{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestGetDesign_Empty_1.json"), &models.Design{})
+}
+
+func TestDesign_Formats(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	header := map[string]string{
+		"Content-Type": "application/x-yaml",
+		"Accept":       "application/x-yaml",
+	}
+
+	responseText, code, _ := ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), header, LoadExpectation(t, "design/TestDesign_Formats_1.yaml"))
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Formats_2.yaml"))
+
+	responseText, code, responseHeader := ExecuteRaw(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), header, nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Formats_3.yaml"))
+	CheckResponseHeader(t, responseHeader, "Content-Type", "application/x-yaml; charset=utf-8")
+
+	parameters := map[string]string{
+		"format": "toml",
+	}
+
+	responseText, code, _ = ExecuteRaw(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1", parameters), nil, nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Formats_4.toml"))
+
+	responseText, code, _ = ExecuteRaw(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil, nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Formats_5.toml"))
+}
+
+func TestDesign_Bundle(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"template_external_parameters": []*models.TemplateExternalParameter{
+				{
+					ID:         1,
+					TemplateID: 1,
+					Name:       "hostname",
+					Value:      "router1",
+				},
+			},
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "router config",
+					TemplateContent: "hostname {{ .TemplateExternalParameters.hostname }}\n",
+				},
+			},
+		},
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	for _, archive := range []string{"tar", "zip"} {
+		parameters := map[string]string{
+			"format":  "bundle",
+			"archive": archive,
+		}
+
+		bundle, code, _ := ExecuteRaw(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", parameters), nil, nil)
+		if code != http.StatusOK {
+			t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(bundle))
+		}
+
+		files := ReadArchive(t, bundle)
+		CheckResponseText(t, http.StatusOK, http.StatusOK, files["design.yaml"], LoadExpectation(t, "design/TestDesign_Bundle_1.yaml"))
+		CheckResponseText(t, http.StatusOK, http.StatusOK, files["templates/1-router_config.tmpl"], []byte("hostname {{ .TemplateExternalParameters.hostname }}\n"))
+
+		Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+
+		responseText, code, _ := ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", map[string]string{"format": "bundle"}), nil, bundle)
+		if code != http.StatusOK {
+			t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(responseText))
+		}
+
+		responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+		CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Bundle_2.json"), &models.Design{})
+
+		os.Setenv(logics.ArchiveMaxEntrySizeEnv, "16")
+		responseText, code, _ = ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", map[string]string{"format": "bundle"}), nil, bundle)
+		os.Unsetenv(logics.ArchiveMaxEntrySizeEnv)
+		CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestDesign_Bundle_3.json"), &ErrorResponseText{})
+	}
+}
+
+func TestDesign_Upgrade(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	legacyDesign := &models.Design{
+		Content: map[string]interface{}{
+			"test_devices": []map[string]interface{}{
+				{
+					"id":       1,
+					"hostname": "router1",
+				},
+			},
+		},
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "present/diff", nil), legacyDesign)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Upgrade_1.json"), &models.DesignDiff{})
+
+	upgradedDesign := &models.Design{
+		FormatVersion: models.DesignFormatVersion,
+		SchemaVersions: map[string]int{
+			"test_devices": 2,
+		},
+		Content: map[string]interface{}{
+			"test_devices": []map[string]interface{}{
+				{
+					"id":   1,
+					"name": "router1",
+				},
+			},
+		},
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "designs", "present/diff", nil), upgradedDesign)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestDesign_Upgrade_1.json"), &models.DesignDiff{})
+
+	newerDesign := &models.Design{
+		FormatVersion: models.DesignFormatVersion,
+		SchemaVersions: map[string]int{
+			"templates": 2,
+		},
+		Content: map[string]interface{}{
+			"templates": []*models.Template{
+				{
+					ID:              1,
+					Name:            "test1",
+					TemplateContent: "TestTemplate1",
+				},
+			},
+		},
+	}
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), newerDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestDesign_Upgrade_2.json"), &ErrorResponseText{})
+
+	newerDesign.FormatVersion = models.DesignFormatVersion + 1
+	newerDesign.SchemaVersions = nil
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), newerDesign)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "design/TestDesign_Upgrade_3.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "design/TestGetDesign_Empty_1.json"), &models.Design{})
+}
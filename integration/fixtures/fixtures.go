@@ -0,0 +1,48 @@
+package fixtures
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/models"
+)
+
+// The extensions used by the integration tests live here, because the integration package shadows the builtin error.
+
+type DesignUpgrade struct {
+}
+
+func (_ *DesignUpgrade) GetDesignKey() string {
+	return "test_devices"
+}
+
+func (_ *DesignUpgrade) GetVersion() int {
+	return 1
+}
+
+func (_ *DesignUpgrade) Upgrade(value interface{}) (interface{}, error) {
+	records := value.([]interface{})
+	for _, record := range records {
+		object := record.(map[string]interface{})
+		object["name"] = object["hostname"]
+		delete(object, "hostname")
+	}
+	return records, nil
+}
+
+type TemplateCountGenerator struct {
+}
+
+func (_ *TemplateCountGenerator) GenerateTemplateParameter(db *gorm.DB) (string, interface{}, error) {
+	count := 0
+	if err := db.Model(&models.Template{}).Count(&count).Error; err != nil {
+		return "", nil, err
+	}
+	return "TestTemplateCount", count, nil
+}
+
+type CollisionGenerator struct {
+	Value string
+}
+
+func (this *CollisionGenerator) GenerateTemplateParameter(_ *gorm.DB) (string, interface{}, error) {
+	return "TestCollision", this.Value, nil
+}
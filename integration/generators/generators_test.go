@@ -0,0 +1,123 @@
+package generators
+
+import (
+	"encoding/json"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/integration"
+	"github.com/qb0C80aE/clay/integration/fixtures"
+	"github.com/qb0C80aE/clay/logics"
+	"github.com/qb0C80aE/clay/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// +build integration
+
+// The generators add parameters to every template, so they are registered only in this package, apart from the other integration tests.
+func init() {
+	extension.RegisterTemplateParameterGenerator(&fixtures.TemplateCountGenerator{})
+	extension.RegisterTemplateParameterGenerator(&fixtures.CollisionGenerator{Value: "first"})
+	extension.RegisterTemplateParameterGenerator(&fixtures.CollisionGenerator{Value: "second"})
+}
+
+func getTemplateParameterCacheStats(t *testing.T, server *httptest.Server) *logics.TemplateParameterCacheStats {
+	responseText, code := integration.Execute(t, http.MethodGet, integration.GenerateMultiResourceUrl(server, "template_parameter_caches", nil), nil)
+	if code != http.StatusOK {
+		t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(responseText))
+	}
+
+	stats := &logics.TemplateParameterCacheStats{}
+	if err := json.Unmarshal(responseText, stats); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestTemplateParameterCache(t *testing.T) {
+	server := integration.SetupServer()
+	defer server.Close()
+
+	before := getTemplateParameterCacheStats(t, server)
+
+	template1 := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "{{.TestTemplateCount}} template(s)",
+	}
+	template2 := &models.Template{
+		ID:              2,
+		Name:            "test2",
+		TemplateContent: "TestTemplate2",
+	}
+	template3 := &models.Template{
+		ID:              3,
+		Name:            "test3",
+		TemplateContent: "TestTemplate3",
+	}
+
+	integration.Execute(t, http.MethodPost, integration.GenerateMultiResourceUrl(server, "templates", nil), template1)
+
+	responseText, code := integration.Execute(t, http.MethodPatch, integration.GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusOK, responseText, []byte("1 template(s)"))
+	responseText, code = integration.Execute(t, http.MethodPatch, integration.GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusOK, responseText, []byte("1 template(s)"))
+
+	integration.Execute(t, http.MethodPost, integration.GenerateMultiResourceUrl(server, "templates", nil), template2)
+
+	responseText, code = integration.Execute(t, http.MethodPatch, integration.GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusOK, responseText, []byte("2 template(s)"))
+
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{template1, template2, template3},
+		},
+	}
+	integration.Execute(t, http.MethodPut, integration.GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	responseText, code = integration.Execute(t, http.MethodPatch, integration.GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusOK, responseText, []byte("3 template(s)"))
+
+	after := getTemplateParameterCacheStats(t, server)
+	if !after.Cached {
+		t.Fatal("the parameters are expected to be cached")
+	}
+	if after.Hits-before.Hits != 1 || after.Misses-before.Misses != 3 || after.Invalidations-before.Invalidations != 3 {
+		t.Fatalf("1 hit, 3 misses and 3 invalidations are expected, but %d, %d and %d", after.Hits-before.Hits, after.Misses-before.Misses, after.Invalidations-before.Invalidations)
+	}
+
+	responseText, code = integration.Execute(t, http.MethodDelete, integration.GenerateMultiResourceUrl(server, "template_parameter_caches", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusNoContent, responseText, []byte{})
+
+	if getTemplateParameterCacheStats(t, server).Cached {
+		t.Fatal("the parameters are expected not to be cached")
+	}
+}
+
+func TestGetTemplateParameters(t *testing.T) {
+	server := integration.SetupServer()
+	defer server.Close()
+
+	template := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "{{.TestCollision}} {{.TemplateExternalParameters.testParameter1}}",
+		TemplateExternalParameters: []*models.TemplateExternalParameter{
+			{
+				Name:  "testParameter1",
+				Value: "TestParameter1",
+			},
+		},
+	}
+
+	integration.Execute(t, http.MethodPost, integration.GenerateMultiResourceUrl(server, "templates", nil), template)
+
+	responseText, code := integration.Execute(t, http.MethodGet, integration.GenerateSingleResourceUrl(server, "templates", "1/parameters", nil), nil)
+	integration.CheckResponseJson(t, code, http.StatusOK, responseText, integration.LoadExpectation(t, "template/TestGetTemplateParameters_1.json"), &logics.TemplateParameters{})
+
+	responseText, code = integration.Execute(t, http.MethodPatch, integration.GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	integration.CheckResponseText(t, code, http.StatusOK, responseText, []byte("second TestParameter1"))
+
+	responseText, code = integration.Execute(t, http.MethodGet, integration.GenerateSingleResourceUrl(server, "templates", "2/parameters", nil), nil)
+	integration.CheckResponseJson(t, code, http.StatusNotFound, responseText, integration.LoadExpectation(t, "template/TestGetTemplateParameters_2.json"), &integration.ErrorResponseText{})
+}
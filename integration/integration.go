@@ -1,11 +1,15 @@
 package integration
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"github.com/qb0C80aE/clay/db"
 	"github.com/qb0C80aE/clay/server"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -23,6 +27,12 @@ type ErrorResponseText struct {
 	Error string `json:"error"`
 }
 
+type ParameterErrorResponseText struct {
+	Error     string `json:"error"`
+	Parameter string `json:"parameter"`
+	Key       string `json:"key"`
+}
+
 func error(t *testing.T, message string, args ...interface{}) {
 	result := fmt.Sprintf(message, args...)
 	t.Fatalf(result)
@@ -67,23 +77,35 @@ func SetupServer() *httptest.Server {
 }
 
 func Execute(t *testing.T, method string, resourceUrl string, data interface{}) ([]byte, int) {
+	contents, code, _ := ExecuteWithHeader(t, method, resourceUrl, data)
+	return contents, code
+}
+
+func ExecuteWithHeader(t *testing.T, method string, resourceUrl string, data interface{}) ([]byte, int, http.Header) {
 	byteArray, err := json.Marshal(data)
 
 	if err != nil {
 		error(t, "error Occured %v", err)
 	}
 
+	return ExecuteRaw(t, method, resourceUrl, map[string]string{"Content-Type": "application/json"}, byteArray)
+}
+
+func ExecuteRaw(t *testing.T, method string, resourceUrl string, header map[string]string, body []byte) ([]byte, int, http.Header) {
 	request, err := http.NewRequest(
 		method,
 		resourceUrl,
-		bytes.NewBuffer(byteArray),
+		bytes.NewBuffer(body),
 	)
-	request.Header.Set("Content-Type", "application/json")
 
 	if err != nil {
 		error(t, "error Occured %v", err)
 	}
 
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
 	client := &http.Client{Timeout: time.Duration(timeout * time.Second)}
 	response, err := client.Do(request)
 
@@ -97,7 +119,7 @@ func Execute(t *testing.T, method string, resourceUrl string, data interface{})
 	if err != nil {
 		error(t, "%s", err)
 	}
-	return contents, response.StatusCode
+	return contents, response.StatusCode, response.Header
 }
 
 func CheckResponseJson(t *testing.T, code int, expectedCode int, responseText []byte, expectedResponseText []byte, model interface{}) {
@@ -154,6 +176,54 @@ func CheckResponseText(t *testing.T, code int, expectedCode int, responseText []
 	}
 }
 
+func CheckResponseHeader(t *testing.T, header http.Header, key string, expectedValue string) {
+	if value := header.Get(key); value != expectedValue {
+		error(t, "header %s is expected as '%s', but '%s'", key, expectedValue, value)
+	}
+}
+
+func ReadArchive(t *testing.T, data []byte) map[string][]byte {
+	files := map[string][]byte{}
+
+	if zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err == nil {
+		for _, file := range zipReader.File {
+			reader, err := file.Open()
+			if err != nil {
+				error(t, "%s", err)
+			}
+			content, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				error(t, "%s", err)
+			}
+			files[file.Name] = content
+		}
+		return files
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		error(t, "%s", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			error(t, "%s", err)
+		}
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			error(t, "%s", err)
+		}
+		files[header.Name] = content
+	}
+
+	return files
+}
+
 func LoadExpectation(t *testing.T, testCaseName string) []byte {
 	expectationFile := fmt.Sprintf("expectations/%s", testCaseName)
 	data, err := ioutil.ReadFile(expectationFile)
@@ -1,8 +1,16 @@
 package integration
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/qb0C80aE/clay/logics"
 	"github.com/qb0C80aE/clay/models"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 )
@@ -201,6 +209,446 @@ func TestPatchTemplate(t *testing.T) {
 	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestPatchTemplate_1.txt"))
 }
 
+func TestRenderTemplate(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	id := 100
+	template := &models.Template{
+		ID:              id,
+		Name:            "test",
+		TemplateContent: "{{.TemplateExternalParameters.testParameter1}} is TestParameter1, {{.TemplateExternalParameters.testParameter2}} is TestParameter2, {{.TemplateExternalParameters.testParameter3}} is TestParameter3.",
+		TemplateExternalParameters: []*models.TemplateExternalParameter{
+			{
+				Name:  "testParameter1",
+				Value: "TestParameter1",
+			},
+			{
+				Name:  "testParameter2",
+				Value: "TestParameter2",
+			},
+		},
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+
+	parameters := map[string]interface{}{
+		"testParameter2": "OverriddenParameter2",
+		"testParameter3": 3,
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", strconv.Itoa(id)+"/render", nil), parameters)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_1.txt"))
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", strconv.Itoa(id)+"/render", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_2.txt"))
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "template_external_parameters", "2", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_3.json"), &models.TemplateExternalParameter{})
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "101/render", nil), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_4.json"), &ErrorResponseText{})
+}
+
+type templateErrorResponseText struct {
+	Error  string                `json:"error"`
+	Detail *logics.TemplateError `json:"detail"`
+}
+
+func TestRenderTemplate_Errors(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	templates := []*models.Template{
+		{
+			ID:              1,
+			Name:            "bad_syntax",
+			TemplateContent: "hostname {{.TemplateExternalParameters.hostname}\n",
+		},
+		{
+			ID:              2,
+			Name:            "bad_call",
+			TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}\ninterfaces {{len .TemplateExternalParameters.hostname}}\n",
+		},
+		{
+			ID:              3,
+			Name:            "typo",
+			TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}\nrouter-id {{.TemplateExternalParameters.routerID}}\n",
+		},
+	}
+
+	for _, template := range templates {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	parameters := map[string]interface{}{
+		"hostname": 1,
+	}
+
+	responseText, code := Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Errors_1.json"), &templateErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "2/render", nil), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Errors_2.json"), &templateErrorResponseText{})
+
+	parameters["hostname"] = "router1"
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "3/render", nil), parameters)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_Errors_3.txt"))
+
+	strict := map[string]string{
+		"strict": "",
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "3/render", strict), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Errors_4.json"), &templateErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "3", strict), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Errors_5.json"), &templateErrorResponseText{})
+}
+
+func TestRenderTemplate_Includes(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	templates := []*models.Template{
+		{
+			ID:              1,
+			Name:            "header",
+			TemplateContent: "! {{.TemplateExternalParameters.hostname}}\n",
+		},
+		{
+			ID:              2,
+			Name:            "interfaces",
+			TemplateContent: "{{template \"header\" .}}{{range .TemplateExternalParameters.interfaces}}interface {{.}}\n{{end}}",
+		},
+		{
+			ID:              3,
+			Name:            "router",
+			TemplateContent: "{{define \"footer\"}}end\n{{end}}{{template \"interfaces\" .}}{{template \"footer\"}}",
+		},
+		{
+			ID:              4,
+			Name:            "missing",
+			TemplateContent: "{{template \"header\" .}}{{template \"banner\" .}}",
+		},
+		{
+			ID:              5,
+			Name:            "loop_a",
+			TemplateContent: "{{template \"loop_b\" .}}",
+		},
+		{
+			ID:              6,
+			Name:            "loop_b",
+			TemplateContent: "{{if .}}\n{{template \"loop_a\" .}}{{end}}",
+		},
+	}
+
+	for _, template := range templates {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	parameters := map[string]interface{}{
+		"hostname":   "router1",
+		"interfaces": []string{"eth0", "eth1"},
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "3/render", nil), parameters)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_Includes_1.txt"))
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "4/render", nil), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Includes_2.json"), &templateErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "5/render", nil), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Includes_3.json"), &templateErrorResponseText{})
+}
+
+func TestRenderTemplate_Functions(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template := &models.Template{
+		ID:              1,
+		Name:            "functions",
+		TemplateContent: "{{$p := .TemplateExternalParameters}}hostname {{$p.hostname | lower | replace \"-\" \"_\"}}\n{{range $i, $interface := $p.interfaces}}interface {{$interface}}\n ip address {{$p.network | ParseCIDR | MinimumHost | IncreaseHostAddress | StringWithPrefix}} index {{add $i 1}}\n{{end}}max host {{$p.network | ParseCIDR | MaxHost | String}}\nnext network {{$p.network | ParseCIDR | Network | IncreaseNetworkAddress | StringWithPrefix}}\nvlans {{join \",\" $p.vlans}} last {{last $p.vlans}} total {{sub (mul (len $p.vlans) 10) 5}}\nkeys {{keys $p.options | toJSON}} mtu {{$p.options.mtu | default 1500}} domain {{$p.options.domain | default \"example.com\"}}\n{{dict \"name\" $p.hostname \"vlans\" $p.vlans | toYAML}}{{if regexMatch \"^eth[0-9]+$\" (first $p.interfaces)}}serial {{regexReplaceAll \"[^0-9]\" \"\" $p.serial}}{{end}}\n",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+
+	parameters := map[string]interface{}{
+		"hostname":   "Router-1",
+		"interfaces": []string{"eth0", "eth1"},
+		"network":    "192.168.1.0/24",
+		"vlans":      []int{10, 20, 30},
+		"options": map[string]interface{}{
+			"domain": "",
+			"mtu":    9000,
+		},
+		"serial": "SN-12-34",
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/render", nil), parameters)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestRenderTemplate_Functions_1.txt"))
+
+	parameters["network"] = "2001:db8::/64"
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/render", nil), parameters)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplate_Functions_2.json"), &templateErrorResponseText{})
+
+	limitedTemplates := []*models.Template{
+		{
+			ID:              2,
+			Name:            "until",
+			TemplateContent: "{{range until 10001}}{{.}}{{end}}",
+		},
+		{
+			ID:              3,
+			Name:            "repeat",
+			TemplateContent: "{{repeat 5001 \"ab\"}}",
+		},
+	}
+
+	for i, limitedTemplate := range limitedTemplates {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), limitedTemplate)
+
+		responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", fmt.Sprintf("%d/render", limitedTemplate.ID), nil), nil)
+		CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, fmt.Sprintf("template/TestRenderTemplate_Functions_%d.json", i+3)), &templateErrorResponseText{})
+	}
+}
+
+func TestRenderTemplates_Archive(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	templates := []*models.Template{
+		{
+			ID:              1,
+			Name:            "router1",
+			TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}\n",
+			TemplateExternalParameters: []*models.TemplateExternalParameter{
+				{
+					Name:  "hostname",
+					Value: "router1",
+				},
+			},
+		},
+		{
+			ID:              2,
+			Name:            "router2",
+			TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}\n",
+		},
+		{
+			ID:              3,
+			Name:            "router3",
+			TemplateContent: "interfaces {{len .TemplateExternalParameters.hostname}\n",
+		},
+		{
+			ID:              4,
+			Name:            "switch1",
+			TemplateContent: "hostname switch1\n",
+		},
+	}
+
+	for _, template := range templates {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	for _, archive := range []string{"tar", "zip"} {
+		parameters := map[string]string{
+			"q[name][like]": "router%25",
+			"archive":       archive,
+			"strict":        "",
+		}
+
+		data, code, _ := ExecuteRaw(t, http.MethodPatch, GenerateMultiResourceUrl(server, "templates", parameters), nil, nil)
+		if code != http.StatusOK {
+			t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(data))
+		}
+
+		files := ReadArchive(t, data)
+		if len(files) != 2 {
+			t.Fatalf("2 files are expected in the archive, but %d", len(files))
+		}
+		CheckResponseText(t, http.StatusOK, http.StatusOK, files["manifest.yaml"], LoadExpectation(t, "template/TestRenderTemplates_Archive_1.yaml"))
+		CheckResponseText(t, http.StatusOK, http.StatusOK, files["router1"], []byte("hostname router1\n"))
+	}
+
+	parameters := map[string]string{
+		"archive": "rar",
+	}
+
+	responseText, code, _ := ExecuteRaw(t, http.MethodPatch, GenerateMultiResourceUrl(server, "templates", parameters), nil, nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestRenderTemplates_Archive_2.json"), &ErrorResponseText{})
+}
+
+func TestGetTemplates_Pagination(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	for i := 1; i <= 3; i++ {
+		template := &models.Template{
+			Name:            fmt.Sprintf("test%d", i),
+			TemplateContent: fmt.Sprintf("TestTemplate%d", i),
+		}
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	parameters := map[string]string{
+		"limit": "2",
+	}
+
+	responseText, code, header := ExecuteWithHeader(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Pagination_1.json"), []*models.Template{})
+	CheckResponseHeader(t, header, "X-Total-Count", "3")
+	CheckResponseHeader(t, header, "Link", fmt.Sprintf(
+		"<%[1]s/%[2]s/templates?limit=2&offset=2>; rel=\"next\", <%[1]s/%[2]s/templates?limit=2>; rel=\"first\", <%[1]s/%[2]s/templates?limit=2&offset=2>; rel=\"last\"",
+		server.URL, version))
+
+	parameters = map[string]string{
+		"limit":  "2",
+		"offset": "2",
+	}
+
+	responseText, code, header = ExecuteWithHeader(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Pagination_2.json"), []*models.Template{})
+	CheckResponseHeader(t, header, "X-Total-Count", "3")
+	CheckResponseHeader(t, header, "Link", fmt.Sprintf(
+		"<%[1]s/%[2]s/templates?limit=2&offset=0>; rel=\"prev\", <%[1]s/%[2]s/templates?limit=2>; rel=\"first\", <%[1]s/%[2]s/templates?limit=2&offset=2>; rel=\"last\"",
+		server.URL, version))
+
+	parameters = map[string]string{
+		"limit": "2",
+		"after": "1",
+	}
+
+	responseText, code, header = ExecuteWithHeader(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Pagination_3.json"), []*models.Template{})
+	CheckResponseHeader(t, header, "X-Total-Count", "3")
+	CheckResponseHeader(t, header, "Link", fmt.Sprintf("<%s/%s/templates?after=3&limit=2>; rel=\"next\"", server.URL, version))
+}
+
+func TestGetTemplates_Filter(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	for _, name := range []string{"core-1", "core-2", "edge-1"} {
+		template := &models.Template{
+			Name:            name,
+			TemplateContent: "TestTemplate",
+		}
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	parameters := map[string]string{
+		"q[name][like]": "core-%25",
+		"q[id][gt]":     "1",
+	}
+
+	responseText, code := Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Filter_1.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"q[or][g][name]":   "edge-1",
+		"q[or][g][id][lt]": "2",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Filter_2.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"q[name][ne]":   "core-1",
+		"q[name][null]": "false",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Filter_3.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"q[unexisted_field][eq]": "1",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestGetTemplates_Filter_4.json"), &ParameterErrorResponseText{})
+}
+
+func TestGetTemplates_FilterAssociation(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template1 := &models.Template{
+		Name:            "test1",
+		TemplateContent: "TestTemplate1",
+		TemplateExternalParameters: []*models.TemplateExternalParameter{
+			{
+				Name:  "foo",
+				Value: "TestParameter11",
+			},
+		},
+	}
+
+	template2 := &models.Template{
+		Name:            "test2",
+		TemplateContent: "TestTemplate2",
+		TemplateExternalParameters: []*models.TemplateExternalParameter{
+			{
+				Name:  "bar",
+				Value: "TestParameter21",
+			},
+		},
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template1)
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template2)
+
+	parameters := map[string]string{
+		"q[template_external_parameters.name]": "foo",
+	}
+
+	responseText, code := Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_FilterAssociation_1.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"q[template_external_parameters.value][like]": "TestParameter2%25",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_FilterAssociation_2.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"q[template_external_parameters.unexisted_field]": "foo",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestGetTemplates_FilterAssociation_3.json"), &ParameterErrorResponseText{})
+}
+
+func TestGetTemplates_Sort(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	for _, name := range []string{"b", "c", "a"} {
+		template := &models.Template{
+			Name:            name,
+			TemplateContent: "TestTemplate",
+		}
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	parameters := map[string]string{
+		"sort": "-name",
+	}
+
+	responseText, code := Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestGetTemplates_Sort_1.json"), []*models.Template{})
+
+	parameters = map[string]string{
+		"sort": "nme",
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateMultiResourceUrl(server, "templates", parameters), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestGetTemplates_Sort_2.json"), &ParameterErrorResponseText{})
+}
+
 func TestGetTemplateExternalParameters_Empty(t *testing.T) {
 	server := SetupServer()
 	defer server.Close()
@@ -537,3 +985,554 @@ func TestTemplate_GenerateTemplateParameter(t *testing.T) {
 	responseText, code := Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", strconv.Itoa(id), nil), nil)
 	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplate_GenerateTemplateParameter_1.txt"))
 }
+
+func TestTemplateExternalParameters_Types(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "{{$p := .TemplateExternalParameters}}{{$p.hostname}} {{add $p.asn 1}} {{if $p.enabled}}enabled{{end}}{{range $p.vlans}} {{.id}}:{{.name}}{{end}}",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+
+	templateExternalParameters := []*models.TemplateExternalParameter{
+		{
+			TemplateID: 1,
+			Name:       "hostname",
+			Value:      "router1",
+		},
+		{
+			TemplateID: 1,
+			Name:       "asn",
+			Type:       "int",
+			Value:      "65000",
+		},
+		{
+			TemplateID: 1,
+			Name:       "enabled",
+			Type:       "bool",
+			Value:      "true",
+		},
+		{
+			TemplateID: 1,
+			Name:       "vlans",
+			Type:       "json",
+			Value:      `[{"id": 10, "name": "users"}, {"id": 20, "name": "servers"}]`,
+		},
+	}
+
+	for _, templateExternalParameter := range templateExternalParameters {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_external_parameters", nil), templateExternalParameter)
+	}
+
+	responseText, code := Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("router1 65001 enabled 10:users 20:servers"))
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/parameters", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateExternalParameters_Types_1.json"), &logics.TemplateParameters{})
+
+	invalidTemplateExternalParameter := &models.TemplateExternalParameter{
+		TemplateID: 1,
+		Name:       "mtu",
+		Type:       "int",
+		Value:      "jumbo",
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_external_parameters", nil), invalidTemplateExternalParameter)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestTemplateExternalParameters_Types_2.json"), &ErrorResponseText{})
+
+	invalidTemplateExternalParameter.Type = "float"
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "template_external_parameters", "2", nil), invalidTemplateExternalParameter)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestTemplateExternalParameters_Types_3.json"), &ErrorResponseText{})
+}
+
+func TestParameterSets(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	templates := []*models.Template{
+		{
+			ID:              1,
+			Name:            "test1",
+			TemplateContent: "{{.Parameters.hostname}} {{.Parameters.domain}} {{.Parameters.ntp}}",
+		},
+		{
+			ID:              2,
+			Name:            "test2",
+			TemplateContent: "{{.Parameters.hostname}} {{.Parameters.domain}} {{.Parameters.ntp}}",
+		},
+	}
+
+	for _, template := range templates {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+	}
+
+	templateExternalParameter := &models.TemplateExternalParameter{
+		TemplateID: 1,
+		Name:       "hostname",
+		Value:      "router1",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_external_parameters", nil), templateExternalParameter)
+
+	parameterSets := []*models.ParameterSet{
+		{
+			ID:       1,
+			Name:     "site",
+			Priority: 10,
+			Parameters: models.ParameterSetParameters{
+				"ntp": "10.0.0.2",
+			},
+		},
+		{
+			ID:     2,
+			Name:   "common",
+			Global: true,
+			Parameters: models.ParameterSetParameters{
+				"hostname": "default",
+				"domain":   "example.com",
+				"ntp":      "10.0.0.1",
+			},
+		},
+	}
+
+	for _, parameterSet := range parameterSets {
+		Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "parameter_sets", nil), parameterSet)
+	}
+
+	templateParameterSet := &models.TemplateParameterSet{
+		TemplateID:     1,
+		ParameterSetID: 1,
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_parameter_sets", nil), templateParameterSet)
+	CheckResponseJson(t, code, http.StatusCreated, responseText, LoadExpectation(t, "template/TestParameterSets_1.json"), &models.TemplateParameterSet{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "parameter_sets", "2", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestParameterSets_2.json"), &models.ParameterSet{})
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("router1 example.com 10.0.0.2"))
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "2", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("default example.com 10.0.0.1"))
+
+	parameters := map[string]interface{}{
+		"ntp": "10.0.0.3",
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/render", nil), parameters)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("router1 example.com 10.0.0.3"))
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestParameterSets_3.json"), &models.Design{})
+
+	design := responseText
+
+	Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestParameterSets_4.json"), &ErrorResponseText{})
+
+	header := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), header, design)
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("router1 example.com 10.0.0.2"))
+
+	responseText, code = Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "parameter_sets", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusNoContent, responseText, []byte{})
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("router1 example.com 10.0.0.1"))
+}
+
+func TestPublishTemplate(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	outputRoot, err := ioutil.TempDir("", "clay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outputRoot)
+	os.Setenv(logics.TemplateOutputRootEnv, outputRoot)
+	defer os.Unsetenv(logics.TemplateOutputRootEnv)
+
+	received := []string{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer webhook.Close()
+
+	webhookURL, err := url.Parse(webhook.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv(logics.TemplateOutputWebhookHostsEnv, "example.com, "+webhookURL.Host)
+	defer os.Unsetenv(logics.TemplateOutputWebhookHostsEnv)
+
+	template := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template)
+
+	templateExternalParameter := &models.TemplateExternalParameter{
+		TemplateID: 1,
+		Name:       "hostname",
+		Value:      "router1",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_external_parameters", nil), templateExternalParameter)
+
+	templateOutputTargets := []*models.TemplateOutputTarget{
+		{
+			TemplateID:  1,
+			Type:        models.TemplateOutputTargetTypeFile,
+			Destination: "configs/router1.conf",
+		},
+		{
+			TemplateID:  1,
+			Type:        models.TemplateOutputTargetTypeWebhook,
+			Destination: webhook.URL + "/configs",
+		},
+		{
+			TemplateID:  1,
+			Type:        models.TemplateOutputTargetTypeWebhook,
+			Destination: webhook.URL + "/fail",
+			Status:      models.TemplateOutputTargetStatusSucceeded,
+		},
+	}
+
+	for _, templateOutputTarget := range templateOutputTargets {
+		responseText, code := Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_output_targets", nil), templateOutputTarget)
+		if code != http.StatusCreated {
+			t.Fatalf("code is expected as %d, but %d: %s", http.StatusCreated, code, string(responseText))
+		}
+		created := &models.TemplateOutputTarget{}
+		if err := json.Unmarshal(responseText, created); err != nil {
+			t.Fatal(err)
+		}
+		if created.Status != "" {
+			t.Fatalf("status of target %d is expected to be ignored on create, but '%s'", created.ID, created.Status)
+		}
+	}
+
+	responseText, code := Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/publish", nil), nil)
+	if code != http.StatusOK {
+		t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(responseText))
+	}
+
+	published := []*models.TemplateOutputTarget{}
+	if err := json.Unmarshal(responseText, &published); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedStatuses := []string{
+		models.TemplateOutputTargetStatusSucceeded,
+		models.TemplateOutputTargetStatusSucceeded,
+		models.TemplateOutputTargetStatusFailed,
+	}
+	if len(published) != len(expectedStatuses) {
+		t.Fatalf("%d targets are expected, but %d", len(expectedStatuses), len(published))
+	}
+	for i, templateOutputTarget := range published {
+		if templateOutputTarget.Status != expectedStatuses[i] {
+			t.Fatalf("status of target %d is expected as '%s', but '%s', %s", templateOutputTarget.ID, expectedStatuses[i], templateOutputTarget.Status, templateOutputTarget.Error)
+		}
+		if templateOutputTarget.PublishedAt == nil {
+			t.Fatalf("published_at of target %d is expected to be set", templateOutputTarget.ID)
+		}
+	}
+	if published[2].Error != "webhook responded 500 Internal Server Error." {
+		t.Fatalf("error of target 3 is expected as the response status, but '%s'", published[2].Error)
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(outputRoot, "configs", "router1.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hostname router1" {
+		t.Fatalf("file is expected as 'hostname router1', but '%s'", string(content))
+	}
+	if len(received) != 1 || received[0] != "hostname router1" {
+		t.Fatalf("webhook is expected to receive 'hostname router1', but %v", received)
+	}
+
+	stored := &models.TemplateOutputTarget{}
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "template_output_targets", "3", nil), nil)
+	if err := json.Unmarshal(responseText, stored); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || stored.Status != models.TemplateOutputTargetStatusFailed || stored.Error != published[2].Error {
+		t.Fatalf("the result of the publish is expected to be recorded, but %s", string(responseText))
+	}
+
+	stored.Status = models.TemplateOutputTargetStatusSucceeded
+	stored.Error = ""
+	stored.PublishedAt = nil
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "template_output_targets", "3", nil), stored)
+
+	updated := &models.TemplateOutputTarget{}
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "template_output_targets", "3", nil), nil)
+	if err := json.Unmarshal(responseText, updated); err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK || updated.Status != models.TemplateOutputTargetStatusFailed || updated.Error != published[2].Error || updated.PublishedAt == nil {
+		t.Fatalf("the result of the publish is expected to be kept on update, but %s", string(responseText))
+	}
+
+	invalidTemplateOutputTarget := &models.TemplateOutputTarget{
+		TemplateID:  1,
+		Type:        models.TemplateOutputTargetTypeFile,
+		Destination: "../router1.conf",
+	}
+
+	responseText, code = Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_output_targets", nil), invalidTemplateOutputTarget)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestPublishTemplate_1.json"), &ErrorResponseText{})
+
+	invalidTemplateOutputTarget.Type = "ftp"
+
+	responseText, code = Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_output_targets", nil), invalidTemplateOutputTarget)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestPublishTemplate_2.json"), &ErrorResponseText{})
+
+	invalidTemplateOutputTarget.Type = models.TemplateOutputTargetTypeWebhook
+	invalidTemplateOutputTarget.Destination = "http://169.254.169.254/latest/meta-data"
+
+	responseText, code = Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_output_targets", nil), invalidTemplateOutputTarget)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestPublishTemplate_4.json"), &ErrorResponseText{})
+
+	outside, err := ioutil.TempDir("", "clay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	if err := os.Symlink(outside, filepath.Join(outputRoot, "outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	invalidTemplateOutputTarget.Type = models.TemplateOutputTargetTypeFile
+	invalidTemplateOutputTarget.Destination = "outside/router1.conf"
+
+	responseText, code = Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_output_targets", nil), invalidTemplateOutputTarget)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestPublishTemplate_5.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "101/publish", nil), nil)
+	CheckResponseJson(t, code, http.StatusBadRequest, responseText, LoadExpectation(t, "template/TestPublishTemplate_3.json"), &ErrorResponseText{})
+}
+
+type templateRevisionWithoutCreatedAt struct {
+	ID              int    `json:"id"`
+	TemplateID      int    `json:"template_id"`
+	Revision        int    `json:"revision"`
+	Author          string `json:"author"`
+	ContentHash     string `json:"content_hash"`
+	TemplateContent string `json:"template_content"`
+}
+
+func TestTemplateRevisions(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "hostname {{.TemplateExternalParameters.hostname}}\nntp 10.0.0.1\n",
+	}
+
+	body, _ := json.Marshal(template)
+	header := map[string]string{
+		"Content-Type":  "application/json",
+		"X-Clay-Author": "alice",
+	}
+	ExecuteRaw(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), header, body)
+
+	templateExternalParameter := &models.TemplateExternalParameter{
+		TemplateID: 1,
+		Name:       "hostname",
+		Value:      "router1",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "template_external_parameters", nil), templateExternalParameter)
+
+	template.TemplateContent = "hostname {{.TemplateExternalParameters.hostname}}\nntp 10.0.0.2\n"
+	body, _ = json.Marshal(template)
+	header["X-Clay-Author"] = "bob"
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "templates", "1", nil), header, body)
+
+	template.Name = "router"
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "templates", "1", nil), template)
+
+	responseText, code := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_1.json"), &[]*templateRevisionWithoutCreatedAt{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions/1", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_2.json"), &templateRevisionWithoutCreatedAt{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions/1/diff/2", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_3.txt"))
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/revisions/1/render", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("hostname router1\nntp 10.0.0.1\n"))
+
+	responseText, code = Execute(t, http.MethodPost, GenerateSingleResourceUrl(server, "templates", "1/revisions/1/restore", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_4.json"), &models.Template{})
+
+	responseText, code = Execute(t, http.MethodPatch, GenerateSingleResourceUrl(server, "templates", "1", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, []byte("hostname router1\nntp 10.0.0.1\n"))
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_5.json"), &[]*templateRevisionWithoutCreatedAt{})
+
+	revisions := responseText
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions/9", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "template/TestTemplateRevisions_6.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "test1/revisions/1", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "template/TestTemplateRevisions_7.json"), &ErrorResponseText{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1%20or%201=1/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "template/TestTemplateRevisions_8.json"), &ErrorResponseText{})
+
+	design := &models.Design{}
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	if err := json.Unmarshal(responseText, design); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := design.Content["template_revisions"]; exists {
+		t.Fatalf("template_revisions is expected to be left out of the design, but %s", string(responseText))
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", map[string]string{"optional": ""}), nil)
+	if err := json.Unmarshal(responseText, design); err != nil {
+		t.Fatal(err)
+	}
+	if templateRevisions, ok := design.Content["template_revisions"].([]interface{}); !ok || len(templateRevisions) != 3 {
+		t.Fatalf("template_revisions is expected to be in the design, but %s", string(responseText))
+	}
+
+	Execute(t, http.MethodDelete, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+
+	header = map[string]string{
+		"Content-Type": "application/json",
+	}
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), header, responseText)
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, revisions)
+}
+
+func TestTemplateRevisions_DesignRoundTrip(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template1 := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "ntp 10.0.0.1\n",
+	}
+	template2 := &models.Template{
+		ID:              2,
+		Name:            "test2",
+		TemplateContent: "ntp 10.0.0.2\n",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template1)
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template2)
+
+	template1.TemplateContent = "ntp 10.0.0.3\n"
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "templates", "1", nil), template1)
+
+	revisions, _ := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+
+	design := &models.Design{}
+	responseText, _ := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "designs", "present", nil), nil)
+	if err := json.Unmarshal(responseText, design); err != nil {
+		t.Fatal(err)
+	}
+
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+
+	responseText, code := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, revisions)
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "designs", nil), &models.DesignSnapshot{Name: "snapshot1"})
+	Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", map[string]string{"from": "1"}), nil)
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, revisions)
+
+	design.Content["templates"] = design.Content["templates"].([]interface{})[:1]
+
+	responseText, code = Execute(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), design)
+	if code != http.StatusOK {
+		t.Fatalf("code is expected as %d, but %d: %s", http.StatusOK, code, string(responseText))
+	}
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseText(t, code, http.StatusOK, responseText, revisions)
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "2/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusNotFound, responseText, LoadExpectation(t, "template/TestTemplateRevisions_DesignRoundTrip_1.json"), &ErrorResponseText{})
+}
+
+func TestTemplateRevisions_DesignImport(t *testing.T) {
+	server := SetupServer()
+	defer server.Close()
+
+	template1 := &models.Template{
+		ID:              1,
+		Name:            "test1",
+		TemplateContent: "ntp 10.0.0.1\n",
+	}
+
+	Execute(t, http.MethodPost, GenerateMultiResourceUrl(server, "templates", nil), template1)
+
+	template1.TemplateContent = "ntp 10.0.0.2\n"
+	design := &models.Design{
+		Content: map[string]interface{}{
+			"templates": []*models.Template{template1},
+		},
+	}
+
+	body, _ := json.Marshal(design)
+	header := map[string]string{
+		"Content-Type":  "application/json",
+		"X-Clay-Author": "carol",
+	}
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", map[string]string{"mode": "merge"}), header, body)
+
+	template1.TemplateContent = "ntp 10.0.0.3\n"
+	template2 := &models.Template{
+		ID:              2,
+		Name:            "test2",
+		TemplateContent: "ntp 10.0.0.4\n",
+	}
+	design.Content["templates"] = []*models.Template{template1, template2}
+
+	body, _ = json.Marshal(design)
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), header, body)
+	ExecuteRaw(t, http.MethodPut, GenerateSingleResourceUrl(server, "designs", "present", nil), header, body)
+
+	responseText, code := Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "1/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_DesignImport_1.json"), &[]*templateRevisionWithoutCreatedAt{})
+
+	responseText, code = Execute(t, http.MethodGet, GenerateSingleResourceUrl(server, "templates", "2/revisions", nil), nil)
+	CheckResponseJson(t, code, http.StatusOK, responseText, LoadExpectation(t, "template/TestTemplateRevisions_DesignImport_2.json"), &[]*templateRevisionWithoutCreatedAt{})
+}
@@ -0,0 +1,180 @@
+package logics
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/qb0C80aE/clay/helper"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveMaxEntrySizeEnv     = "ARCHIVE_MAX_ENTRY_SIZE"
+	ArchiveMaxEntrySizeDefault = 10 * 1024 * 1024
+)
+
+var archiveFileNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func archiveFileName(name string) string {
+	return archiveFileNamePattern.ReplaceAllString(name, "_")
+}
+
+func validateArchive(archive string) error {
+	if archive != helper.ArchiveTar && archive != helper.ArchiveZip {
+		return fmt.Errorf("Invalid Parameter. archive must be tar or zip, but '%s'.", archive)
+	}
+	return nil
+}
+
+func writeArchive(archive string, paths []string, files map[string][]byte) ([]byte, error) {
+	if err := validateArchive(archive); err != nil {
+		return nil, err
+	}
+	if archive == helper.ArchiveZip {
+		return writeZipArchive(paths, files)
+	}
+	return writeTarArchive(paths, files)
+}
+
+func writeTarArchive(paths []string, files map[string][]byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, filePath := range paths {
+		header := &tar.Header{
+			Name:    filePath,
+			Mode:    0644,
+			Size:    int64(len(files[filePath])),
+			ModTime: time.Now(),
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(files[filePath]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func writeZipArchive(paths []string, files map[string][]byte) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+
+	for _, filePath := range paths {
+		writer, err := zipWriter.Create(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(files[filePath]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func archivePath(name string) string {
+	// Archives made by hand often prefix the entries with "./".
+	return path.Clean(strings.TrimPrefix(name, "./"))
+}
+
+func archiveMaxEntrySize() int64 {
+	if size, err := strconv.ParseInt(os.Getenv(ArchiveMaxEntrySizeEnv), 10, 64); err == nil && size > 0 {
+		return size
+	}
+	return ArchiveMaxEntrySizeDefault
+}
+
+// readArchiveEntry reads one more byte than the maximum, so that larger entries are rejected without being read entirely.
+func readArchiveEntry(name string, reader io.Reader) ([]byte, error) {
+	maxSize := archiveMaxEntrySize()
+	content, err := ioutil.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, fmt.Errorf("Invalid Bundle. %s exceeds the maximum entry size of %d bytes.", name, maxSize)
+	}
+	return content, nil
+}
+
+func readArchive(data []byte) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range zipReader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			reader, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := readArchiveEntry(file.Name, reader)
+			reader.Close()
+			if err != nil {
+				return nil, err
+			}
+			files[archivePath(file.Name)] = content
+		}
+		return files, nil
+	}
+
+	var reader io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte("\x1f\x8b")) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid Bundle. %s", err.Error())
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		content, err := readArchiveEntry(header.Name, tarReader)
+		if err != nil {
+			return nil, err
+		}
+		files[archivePath(header.Name)] = content
+	}
+
+	return files, nil
+}
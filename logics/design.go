@@ -1,18 +1,43 @@
 package logics
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/qb0C80aE/clay/extension"
 	"github.com/qb0C80aE/clay/models"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+const PresentDesignID = "present"
+
+// DesignOptionalContentKey is set to the db instance with gorm.DB.Set to extract the optional sections of the design,
+// whose accessors return nil from ExtractFromDesign without it.
+const DesignOptionalContentKey = "clay:design_optional_content"
+
+const (
+	DesignImportModeReplace = "replace"
+	DesignImportModeMerge   = "merge"
+	DesignImportModePatch   = "patch"
 )
 
 type DesignLogic struct {
 }
 
+type DesignSnapshotLogic struct {
+}
+
 func NewDesignLogic() *DesignLogic {
 	return &DesignLogic{}
 }
 
+func NewDesignSnapshotLogic() *DesignSnapshotLogic {
+	return &DesignSnapshotLogic{}
+}
+
 func (_ *DesignLogic) GetSingle(db *gorm.DB, _ string, _ string) (interface{}, error) {
 
 	design := &models.Design{
@@ -25,9 +50,15 @@ func (_ *DesignLogic) GetSingle(db *gorm.DB, _ string, _ string) (interface{}, e
 		if err != nil {
 			return nil, err
 		}
+		if value == nil {
+			continue
+		}
 		design.Content[key] = value
 	}
 
+	design.FormatVersion = models.DesignFormatVersion
+	design.SchemaVersions = designSchemaVersions(design)
+
 	return design, nil
 }
 
@@ -39,28 +70,234 @@ func (this *DesignLogic) Create(db *gorm.DB, data interface{}) (interface{}, err
 	return nil, nil
 }
 
-func (_ *DesignLogic) Update(db *gorm.DB, _ string, data interface{}) (interface{}, error) {
-	design := data.(*models.Design)
+func (this *DesignLogic) Update(db *gorm.DB, _ string, data interface{}) (interface{}, error) {
+	return this.Import(db, data.(*models.Design), DesignImportModeReplace)
+}
 
-	designAccessors := extension.GetDesignAccessos()
+func (this *DesignLogic) Import(db *gorm.DB, design *models.Design, mode string) (*models.Design, error) {
+	// The optional sections are extracted to be kept when they are missing in the design.
+	db = db.Set(DesignOptionalContentKey, true)
+
+	designAccessors, err := sortDesignAccessors(extension.GetDesignAccessos())
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedDesign, err := UpgradeDesign(design)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedDesign, keptKeys, err := this.expectedDesign(db, designAccessors, normalizedDesign, mode)
+	if err != nil {
+		return nil, err
+	}
+	if problems := checkReferences(designAccessors, expectedDesign); len(problems) > 0 {
+		return nil, &DesignValidationError{Problems: problems}
+	}
+
+	if mode == DesignImportModeMerge {
+		for _, accessor := range designAccessors {
+			if err := accessor.MergeToDesign(db, normalizedDesign); err != nil {
+				return nil, err
+			}
+		}
+		return normalizedDesign, nil
+	}
+
+	// The sections which are replaced and the kept ones depending on them are reloaded.
+	// The kept sections are reloaded from their expected records, which are the present ones, to satisfy foreign keys.
+	affectedKeys := map[string]bool{}
 	for _, accessor := range designAccessors {
-		if err := accessor.DeleteFromDesign(db); err != nil {
-			return nil, err
+		key := accessor.GetDesignKey()
+		if !keptKeys[key] {
+			affectedKeys[key] = true
+			continue
+		}
+		for _, dependency := range accessor.GetDesignDependencies() {
+			if affectedKeys[dependency] {
+				affectedKeys[key] = true
+				break
+			}
+		}
+	}
+
+	for i := len(designAccessors) - 1; i >= 0; i-- {
+		if affectedKeys[designAccessors[i].GetDesignKey()] {
+			if err := designAccessors[i].DeleteFromDesign(db); err != nil {
+				return nil, err
+			}
 		}
 	}
 	for _, accessor := range designAccessors {
-		if err := accessor.LoadToDesign(db, design); err != nil {
+		key := accessor.GetDesignKey()
+		if !affectedKeys[key] {
+			continue
+		}
+		source := normalizedDesign
+		if keptKeys[key] {
+			source = expectedDesign
+		}
+		if err := accessor.LoadToDesign(db, source); err != nil {
 			return nil, err
 		}
 	}
 
-	return design, nil
+	return normalizedDesign, nil
 }
 
-func (_ *DesignLogic) Delete(db *gorm.DB, _ string) error {
-	designAccessors := extension.GetDesignAccessos()
+// isOptionalDesignAccessor tells whether the section is optional, which is extracted only with DesignOptionalContentKey.
+func isOptionalDesignAccessor(db *gorm.DB, accessor extension.DesignAccessor) (bool, error) {
+	_, value, err := accessor.ExtractFromDesign(db.Set(DesignOptionalContentKey, false))
+	if err != nil {
+		return false, err
+	}
+	return value == nil, nil
+}
+
+// expectedDesign returns the design after the import, and the keys of the sections whose present records are kept,
+// which are the ones missing in the design except the required ones in the replace mode.
+func (_ *DesignLogic) expectedDesign(db *gorm.DB, designAccessors []extension.DesignAccessor, design *models.Design, mode string) (*models.Design, map[string]bool, error) {
+	switch mode {
+	case DesignImportModeReplace, DesignImportModeMerge, DesignImportModePatch:
+	default:
+		return nil, nil, fmt.Errorf("Invalid Parameter. mode must be replace, merge or patch, but '%s'.", mode)
+	}
+
+	expectedDesign := &models.Design{
+		Content: map[string]interface{}{},
+	}
+	keptKeys := map[string]bool{}
+	optionalKeys := map[string]bool{}
+
 	for _, accessor := range designAccessors {
-		if err := accessor.DeleteFromDesign(db); err != nil {
+		key := accessor.GetDesignKey()
+		value, exists := design.Content[key]
+
+		if !exists && mode != DesignImportModeMerge {
+			optional, err := isOptionalDesignAccessor(db, accessor)
+			if err != nil {
+				return nil, nil, err
+			}
+			optionalKeys[key] = optional
+			keptKeys[key] = mode == DesignImportModePatch || optional
+		}
+
+		if mode != DesignImportModeMerge && !keptKeys[key] {
+			expectedDesign.Content[key] = value
+			continue
+		}
+
+		_, presentValue, err := accessor.ExtractFromDesign(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		expectedDesign.Content[key] = presentValue
+	}
+
+	expectedDesign, err := normalizeDesign(expectedDesign)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if mode == DesignImportModeMerge {
+		for key, value := range design.Content {
+			if _, exists := expectedDesign.Content[key]; exists {
+				expectedDesign.Content[key] = mergeRecords(toRecords(expectedDesign.Content[key]), toRecords(value))
+			}
+		}
+	}
+
+	// The kept optional sections can't be fixed in the design, which doesn't have them,
+	// so their records referring to removed records are dropped like the deletes cascaded by foreign keys.
+	for _, accessor := range designAccessors {
+		key := accessor.GetDesignKey()
+		if !optionalKeys[key] || !keptKeys[key] {
+			continue
+		}
+
+		ids := designRecordIDs(designAccessors, expectedDesign)
+		records := []interface{}{}
+		for _, record := range toRecords(expectedDesign.Content[key]) {
+			if object, ok := record.(map[string]interface{}); ok && len(danglingFields(accessor.GetDesignDependencies(), ids, object)) > 0 {
+				continue
+			}
+			records = append(records, record)
+		}
+		expectedDesign.Content[key] = records
+	}
+
+	return expectedDesign, keptKeys, nil
+}
+
+func mergeRecords(records []interface{}, otherRecords []interface{}) []interface{} {
+	result := []interface{}{}
+	indexes := map[string]int{}
+
+	for _, record := range records {
+		indexes[recordKey(record)] = len(result)
+		result = append(result, record)
+	}
+	for _, otherRecord := range otherRecords {
+		if index, exists := indexes[recordKey(otherRecord)]; exists {
+			result[index] = otherRecord
+			continue
+		}
+		result = append(result, otherRecord)
+	}
+
+	return result
+}
+
+func sortDesignAccessors(designAccessors []extension.DesignAccessor) ([]extension.DesignAccessor, error) {
+	registeredKeys := map[string]bool{}
+	for _, accessor := range designAccessors {
+		registeredKeys[accessor.GetDesignKey()] = true
+	}
+
+	result := []extension.DesignAccessor{}
+	sortedKeys := map[string]bool{}
+	rest := designAccessors
+
+	for len(rest) > 0 {
+		unsorted := []extension.DesignAccessor{}
+		for _, accessor := range rest {
+			ready := true
+			for _, dependency := range accessor.GetDesignDependencies() {
+				if dependency != accessor.GetDesignKey() && registeredKeys[dependency] && !sortedKeys[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				result = append(result, accessor)
+				sortedKeys[accessor.GetDesignKey()] = true
+			} else {
+				unsorted = append(unsorted, accessor)
+			}
+		}
+
+		if len(unsorted) == len(rest) {
+			keys := []string{}
+			for _, accessor := range unsorted {
+				keys = append(keys, accessor.GetDesignKey())
+			}
+			return nil, fmt.Errorf("The design accessors of %v depend on each other.", keys)
+		}
+		rest = unsorted
+	}
+
+	return result, nil
+}
+
+func (_ *DesignLogic) Delete(db *gorm.DB, _ string) error {
+	designAccessors, err := sortDesignAccessors(extension.GetDesignAccessos())
+	if err != nil {
+		return err
+	}
+
+	for i := len(designAccessors) - 1; i >= 0; i-- {
+		if err := designAccessors[i].DeleteFromDesign(db); err != nil {
 			return err
 		}
 	}
@@ -76,7 +313,299 @@ func (_ *DesignLogic) Options(db *gorm.DB) error {
 	return nil
 }
 
+func (this *DesignLogic) LoadDesign(db *gorm.DB, id string) (*models.Design, error) {
+	if id == PresentDesignID {
+		result, err := this.GetSingle(db, id, "*")
+		if err != nil {
+			return nil, err
+		}
+		return normalizeDesign(result.(*models.Design))
+	}
+
+	result, err := DesignSnapshotLogicInstance.GetSingle(db, id, "*")
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.DesignSnapshot).Design, nil
+}
+
+func (this *DesignLogic) Diff(db *gorm.DB, id string, otherID string) (*models.DesignDiff, error) {
+	design, err := this.LoadDesign(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	otherDesign, err := this.LoadDesign(db, otherID)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffDesigns(design, otherDesign), nil
+}
+
+func (this *DesignLogic) DiffWith(db *gorm.DB, id string, otherDesign *models.Design) (*models.DesignDiff, error) {
+	design, err := this.LoadDesign(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	otherDesign, err = UpgradeDesign(otherDesign)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffDesigns(design, otherDesign), nil
+}
+
+func normalizeDesign(design *models.Design) (*models.Design, error) {
+	data, err := json.Marshal(design)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.Design{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	if result.Content == nil {
+		result.Content = map[string]interface{}{}
+	}
+
+	return result, nil
+}
+
+func recordID(record interface{}) (string, interface{}, bool) {
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return "", nil, false
+	}
+
+	id, exists := object["id"]
+	if !exists || id == nil {
+		return "", nil, false
+	}
+
+	return fmt.Sprint(id), id, true
+}
+
+func recordKey(record interface{}) string {
+	if key, _, ok := recordID(record); ok {
+		return "id:" + key
+	}
+
+	// Records without ids can only be matched by their whole content.
+	data, _ := json.Marshal(record)
+	return "record:" + string(data)
+}
+
+func toRecords(value interface{}) []interface{} {
+	if value == nil {
+		return []interface{}{}
+	}
+	if records, ok := value.([]interface{}); ok {
+		return records
+	}
+	return []interface{}{value}
+}
+
+func changedFields(record interface{}, otherRecord interface{}) []string {
+	fields := []string{}
+
+	object, ok := record.(map[string]interface{})
+	if !ok {
+		return fields
+	}
+	otherObject, ok := otherRecord.(map[string]interface{})
+	if !ok {
+		return fields
+	}
+
+	for key, value := range object {
+		if otherValue, exists := otherObject[key]; !exists || !reflect.DeepEqual(value, otherValue) {
+			fields = append(fields, key)
+		}
+	}
+	for key := range otherObject {
+		if _, exists := object[key]; !exists {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func diffResources(records []interface{}, otherRecords []interface{}) *models.ResourceDiff {
+	diff := &models.ResourceDiff{
+		Added:    []interface{}{},
+		Removed:  []interface{}{},
+		Modified: []*models.RecordDiff{},
+	}
+
+	otherRecordMap := map[string]interface{}{}
+	for _, otherRecord := range otherRecords {
+		otherRecordMap[recordKey(otherRecord)] = otherRecord
+	}
+
+	recordMap := map[string]interface{}{}
+	for _, record := range records {
+		key := recordKey(record)
+		recordMap[key] = record
+
+		otherRecord, exists := otherRecordMap[key]
+		if !exists {
+			diff.Removed = append(diff.Removed, record)
+			continue
+		}
+
+		if !reflect.DeepEqual(record, otherRecord) {
+			_, id, _ := recordID(record)
+			diff.Modified = append(diff.Modified, &models.RecordDiff{
+				ID:     id,
+				Fields: changedFields(record, otherRecord),
+				Before: record,
+				After:  otherRecord,
+			})
+		}
+	}
+
+	for _, otherRecord := range otherRecords {
+		if _, exists := recordMap[recordKey(otherRecord)]; !exists {
+			diff.Added = append(diff.Added, otherRecord)
+		}
+	}
+
+	return diff
+}
+
+func diffDesigns(design *models.Design, otherDesign *models.Design) *models.DesignDiff {
+	diff := &models.DesignDiff{
+		Resources: map[string]*models.ResourceDiff{},
+	}
+
+	keys := map[string]bool{}
+	for key := range design.Content {
+		keys[key] = true
+	}
+	for key := range otherDesign.Content {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		resourceDiff := diffResources(toRecords(design.Content[key]), toRecords(otherDesign.Content[key]))
+		if len(resourceDiff.Added)+len(resourceDiff.Removed)+len(resourceDiff.Modified) > 0 {
+			diff.Resources[key] = resourceDiff
+		}
+	}
+
+	return diff
+}
+
+func (_ *DesignSnapshotLogic) GetSingle(db *gorm.DB, id string, queryFields string) (interface{}, error) {
+	snapshot := &models.DesignSnapshot{}
+
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	if err := db.Select(queryFields).First(snapshot, id).Error; err != nil {
+		return nil, err
+	}
+
+	if snapshot.Content != "" {
+		snapshot.Design = &models.Design{}
+		if err := json.Unmarshal([]byte(snapshot.Content), snapshot.Design); err != nil {
+			return nil, err
+		}
+		// Snapshots taken by older versions are upgraded to be restored and compared with the present design.
+		design, err := UpgradeDesign(snapshot.Design)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Design = design
+	}
+
+	return snapshot, nil
+}
+
+func (_ *DesignSnapshotLogic) GetMulti(db *gorm.DB, queryFields string) ([]interface{}, error) {
+	snapshots := []*models.DesignSnapshot{}
+
+	if err := db.Select(queryFields).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(snapshots))
+	for i, data := range snapshots {
+		result[i] = data
+	}
+
+	return result, nil
+}
+
+func (_ *DesignSnapshotLogic) Create(db *gorm.DB, data interface{}) (interface{}, error) {
+	snapshot := data.(*models.DesignSnapshot)
+
+	if snapshot.Name == "" {
+		return nil, errors.New("Invalid Parameter. The snapshot name is required.")
+	}
+
+	design, err := DesignLogicInstance.GetSingle(db, PresentDesignID, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(design)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.ID = 0
+	snapshot.Content = string(content)
+	if err := db.Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot.Design = &models.Design{}
+	if err := json.Unmarshal(content, snapshot.Design); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+func (_ *DesignSnapshotLogic) Update(_ *gorm.DB, _ string, _ interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (_ *DesignSnapshotLogic) Delete(db *gorm.DB, id string) error {
+	snapshot := &models.DesignSnapshot{}
+
+	if _, err := strconv.Atoi(id); err != nil {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := db.First(snapshot, id).Error; err != nil {
+		return err
+	}
+
+	if err := db.Delete(snapshot).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (_ *DesignSnapshotLogic) Patch(_ *gorm.DB, _ string, _ string) (interface{}, error) {
+	return nil, nil
+}
+
+func (_ *DesignSnapshotLogic) Options(db *gorm.DB) error {
+	return nil
+}
+
 var DesignLogicInstance = &DesignLogic{}
+var DesignSnapshotLogicInstance = &DesignSnapshotLogic{}
 
 func init() {
 }
@@ -0,0 +1,115 @@
+package logics
+
+import (
+	"fmt"
+	"github.com/qb0C80aE/clay/helper"
+	"github.com/qb0C80aE/clay/models"
+)
+
+const designBundleManifest = "design.yaml"
+
+type designBundleFile struct {
+	key       string
+	field     string
+	directory string
+	extension string
+}
+
+// The fields which are written as separate files in bundles, instead of the manifest.
+var designBundleFiles = []*designBundleFile{
+	{
+		key:       "templates",
+		field:     "template_content",
+		directory: "templates",
+		extension: ".tmpl",
+	},
+}
+
+func (this *designBundleFile) fileField() string {
+	return this.field + "_file"
+}
+
+func (this *designBundleFile) path(record map[string]interface{}) string {
+	name := archiveFileName(fmt.Sprint(record["name"]))
+	return fmt.Sprintf("%s/%v-%s%s", this.directory, record["id"], name, this.extension)
+}
+
+func (this *DesignLogic) ExportBundle(design *models.Design, archive string) ([]byte, error) {
+	manifest, err := normalizeDesign(design)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	paths := []string{designBundleManifest}
+
+	for _, bundleFile := range designBundleFiles {
+		for _, record := range toRecords(manifest.Content[bundleFile.key]) {
+			object, ok := record.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			content, ok := object[bundleFile.field].(string)
+			if !ok {
+				continue
+			}
+
+			filePath := bundleFile.path(object)
+			if _, exists := files[filePath]; exists {
+				return nil, fmt.Errorf("Invalid Design. %s is generated twice.", filePath)
+			}
+			files[filePath] = []byte(content)
+			paths = append(paths, filePath)
+
+			delete(object, bundleFile.field)
+			object[bundleFile.fileField()] = filePath
+		}
+	}
+
+	files[designBundleManifest], err = helper.MarshalYAML(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeArchive(archive, paths, files)
+}
+
+func (this *DesignLogic) ImportBundle(data []byte) (*models.Design, error) {
+	files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, exists := files[designBundleManifest]
+	if !exists {
+		return nil, fmt.Errorf("Invalid Bundle. %s is not found in the bundle.", designBundleManifest)
+	}
+
+	design := &models.Design{}
+	if err := helper.UnmarshalYAML(manifest, design); err != nil {
+		return nil, err
+	}
+
+	for _, bundleFile := range designBundleFiles {
+		for _, record := range toRecords(design.Content[bundleFile.key]) {
+			object, ok := record.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			filePath, ok := object[bundleFile.fileField()].(string)
+			if !ok {
+				continue
+			}
+
+			content, exists := files[archivePath(filePath)]
+			if !exists {
+				return nil, fmt.Errorf("Invalid Bundle. %s is not found in the bundle.", filePath)
+			}
+
+			delete(object, bundleFile.fileField())
+			object[bundleFile.field] = string(content)
+		}
+	}
+
+	return design, nil
+}
@@ -0,0 +1,76 @@
+package logics
+
+import (
+	"fmt"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/models"
+)
+
+func upgradeDesignSection(key string, version int, value interface{}) (interface{}, error) {
+	current := extension.GetDesignSchemaVersion(key)
+	if version > current {
+		return nil, fmt.Errorf("Invalid Design. The schema version %d of %s is newer than %d, which is supported.", version, key, current)
+	}
+
+	for _, designUpgrade := range extension.GetDesignUpgrades(key) {
+		if designUpgrade.GetVersion() < version {
+			continue
+		}
+		if designUpgrade.GetVersion() != version {
+			return nil, fmt.Errorf("Invalid Design. The upgrade of %s from the schema version %d is not registered.", key, version)
+		}
+
+		upgradedValue, err := designUpgrade.Upgrade(value)
+		if err != nil {
+			return nil, err
+		}
+		value = upgradedValue
+		version++
+	}
+
+	return value, nil
+}
+
+func UpgradeDesign(design *models.Design) (*models.Design, error) {
+	upgradedDesign, err := normalizeDesign(design)
+	if err != nil {
+		return nil, err
+	}
+
+	if upgradedDesign.FormatVersion > models.DesignFormatVersion {
+		return nil, fmt.Errorf("Invalid Design. The format version %d is newer than %d, which is supported.", upgradedDesign.FormatVersion, models.DesignFormatVersion)
+	}
+
+	// Documents without versions are older than the versioning, so every key of them is at the first schema version.
+	for key, value := range upgradedDesign.Content {
+		version := 1
+		if schemaVersion, exists := upgradedDesign.SchemaVersions[key]; exists {
+			version = schemaVersion
+		}
+
+		upgradedValue, err := upgradeDesignSection(key, version, value)
+		if err != nil {
+			return nil, err
+		}
+		upgradedDesign.Content[key] = upgradedValue
+	}
+
+	upgradedDesign.FormatVersion = models.DesignFormatVersion
+	upgradedDesign.SchemaVersions = designSchemaVersions(upgradedDesign)
+
+	return normalizeDesign(upgradedDesign)
+}
+
+func designSchemaVersions(design *models.Design) map[string]int {
+	schemaVersions := map[string]int{}
+
+	for _, accessor := range extension.GetDesignAccessos() {
+		key := accessor.GetDesignKey()
+		schemaVersions[key] = extension.GetDesignSchemaVersion(key)
+	}
+	for key := range design.Content {
+		schemaVersions[key] = extension.GetDesignSchemaVersion(key)
+	}
+
+	return schemaVersions
+}
@@ -0,0 +1,274 @@
+package logics
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/models"
+	"net/http"
+	"sort"
+)
+
+const (
+	DesignProblemUnknownKey        = "unknown_key"
+	DesignProblemInvalidSection    = "invalid_section"
+	DesignProblemDuplicateID       = "duplicate_id"
+	DesignProblemTypeMismatch      = "type_mismatch"
+	DesignProblemDanglingReference = "dangling_reference"
+	DesignProblemLoadFailure       = "load_failure"
+)
+
+type DesignProblem struct {
+	Kind    string      `json:"kind"`
+	Key     string      `json:"key"`
+	ID      interface{} `json:"id,omitempty"`
+	Field   string      `json:"field,omitempty"`
+	Message string      `json:"message"`
+}
+
+type DesignValidationError struct {
+	Problems []*DesignProblem `json:"problems"`
+}
+
+type DesignValidationResult struct {
+	Valid    bool             `json:"valid"`
+	Problems []*DesignProblem `json:"problems"`
+}
+
+func (this *DesignValidationError) Error() string {
+	return fmt.Sprintf("Invalid Design. %d problem(s) found in the design.", len(this.Problems))
+}
+
+func (this *DesignValidationError) GetStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (this *DesignValidationError) GetErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"problems": this.Problems,
+	}
+}
+
+func (this *DesignLogic) Validate(db *gorm.DB, design *models.Design) (*DesignValidationResult, error) {
+	designAccessors, err := sortDesignAccessors(extension.GetDesignAccessos())
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedDesign, err := UpgradeDesign(design)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []*DesignProblem{}
+
+	registeredKeys := map[string]bool{}
+	for _, accessor := range designAccessors {
+		registeredKeys[accessor.GetDesignKey()] = true
+	}
+	unknownKeys := []string{}
+	for key := range normalizedDesign.Content {
+		if !registeredKeys[key] {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	sort.Strings(unknownKeys)
+	for _, key := range unknownKeys {
+		problems = append(problems, &DesignProblem{
+			Kind:    DesignProblemUnknownKey,
+			Key:     key,
+			Message: fmt.Sprintf("%s is not handled by any design accessor.", key),
+		})
+	}
+
+	// The records which already have problems are not loaded, so that they are reported only once.
+	skippedRecords := map[string]map[int]bool{}
+	for _, accessor := range designAccessors {
+		key := accessor.GetDesignKey()
+		skippedRecords[key] = map[int]bool{}
+
+		value, exists := normalizedDesign.Content[key]
+		if !exists || value == nil {
+			continue
+		}
+		records, ok := value.([]interface{})
+		if !ok {
+			problems = append(problems, &DesignProblem{
+				Kind:    DesignProblemInvalidSection,
+				Key:     key,
+				Message: fmt.Sprintf("%s must be an array.", key),
+			})
+			delete(normalizedDesign.Content, key)
+			continue
+		}
+
+		ids := map[string]bool{}
+		for i, record := range records {
+			id, value, ok := recordID(record)
+			if !ok {
+				continue
+			}
+			switch value.(type) {
+			case []interface{}, map[string]interface{}:
+				problems = append(problems, &DesignProblem{
+					Kind:    DesignProblemTypeMismatch,
+					Key:     key,
+					ID:      value,
+					Field:   "id",
+					Message: fmt.Sprintf("id %v in %s must be a number or a string.", id, key),
+				})
+				skippedRecords[key][i] = true
+				continue
+			}
+			if ids[id] {
+				problems = append(problems, &DesignProblem{
+					Kind:    DesignProblemDuplicateID,
+					Key:     key,
+					ID:      value,
+					Message: fmt.Sprintf("id %v is duplicated in %s.", value, key),
+				})
+				skippedRecords[key][i] = true
+			}
+			ids[id] = true
+		}
+	}
+
+	for _, problem := range checkReferences(designAccessors, normalizedDesign) {
+		problems = append(problems, problem)
+		for i, record := range toRecords(normalizedDesign.Content[problem.Key]) {
+			if id, _, ok := recordID(record); ok && id == fmt.Sprint(problem.ID) {
+				skippedRecords[problem.Key][i] = true
+			}
+		}
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	for i := len(designAccessors) - 1; i >= 0; i-- {
+		if err := designAccessors[i].DeleteFromDesign(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, accessor := range designAccessors {
+		key := accessor.GetDesignKey()
+		value, exists := normalizedDesign.Content[key]
+		if !exists || value == nil {
+			continue
+		}
+
+		for i, record := range value.([]interface{}) {
+			if skippedRecords[key][i] {
+				continue
+			}
+
+			// Each record is loaded separately to tell which one is broken.
+			if err := tx.Exec("SAVEPOINT design_validation;").Error; err != nil {
+				return nil, err
+			}
+			recordDesign := &models.Design{
+				Content: map[string]interface{}{
+					key: []interface{}{record},
+				},
+			}
+			if err := accessor.LoadToDesign(tx, recordDesign); err != nil {
+				kind := DesignProblemLoadFailure
+				if _, ok := err.(*json.UnmarshalTypeError); ok {
+					kind = DesignProblemTypeMismatch
+				}
+				_, id, _ := recordID(record)
+				problems = append(problems, &DesignProblem{
+					Kind:    kind,
+					Key:     key,
+					ID:      id,
+					Message: err.Error(),
+				})
+				if err := tx.Exec("ROLLBACK TO SAVEPOINT design_validation;").Error; err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := tx.Exec("RELEASE SAVEPOINT design_validation;").Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := &DesignValidationResult{
+		Valid:    len(problems) == 0,
+		Problems: problems,
+	}
+
+	return result, nil
+}
+
+func designRecordIDs(designAccessors []extension.DesignAccessor, design *models.Design) map[string]map[string]bool {
+	ids := map[string]map[string]bool{}
+	for _, accessor := range designAccessors {
+		key := accessor.GetDesignKey()
+		ids[key] = map[string]bool{}
+		for _, record := range toRecords(design.Content[key]) {
+			if id, _, ok := recordID(record); ok {
+				ids[key][id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// danglingFields returns the fields of the record which refer to records not in ids, in the order of their names.
+func danglingFields(dependencies map[string]string, ids map[string]map[string]bool, object map[string]interface{}) []string {
+	fields := []string{}
+	for field := range dependencies {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	result := []string{}
+	for _, field := range fields {
+		referredIDs, registered := ids[dependencies[field]]
+		value := object[field]
+		if !registered || value == nil {
+			continue
+		}
+		if !referredIDs[fmt.Sprint(value)] {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+func checkReferences(designAccessors []extension.DesignAccessor, design *models.Design) []*DesignProblem {
+	problems := []*DesignProblem{}
+
+	ids := designRecordIDs(designAccessors, design)
+	for _, accessor := range designAccessors {
+		key := accessor.GetDesignKey()
+		dependencies := accessor.GetDesignDependencies()
+
+		for _, record := range toRecords(design.Content[key]) {
+			object, ok := record.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			_, id, _ := recordID(record)
+
+			for _, field := range danglingFields(dependencies, ids, object) {
+				problems = append(problems, &DesignProblem{
+					Kind:    DesignProblemDanglingReference,
+					Key:     key,
+					ID:      id,
+					Field:   field,
+					Message: fmt.Sprintf("%s %v does not exist in %s.", field, object[field], dependencies[field]),
+				})
+			}
+		}
+	}
+
+	return problems
+}
@@ -2,6 +2,7 @@ package logics
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/qb0C80aE/clay/extension"
 	"github.com/qb0C80aE/clay/models"
@@ -49,6 +50,10 @@ func (_ *TemplateExternalParameterLogic) Create(db *gorm.DB, data interface{}) (
 
 	templateExternalParameter := data.(*models.TemplateExternalParameter)
 
+	if err := validateTemplateExternalParameter(templateExternalParameter); err != nil {
+		return nil, err
+	}
+
 	if err := db.Create(templateExternalParameter).Error; err != nil {
 		return nil, err
 	}
@@ -62,6 +67,10 @@ func (_ *TemplateExternalParameterLogic) Update(db *gorm.DB, id string, data int
 	templateExternalParameter := data.(*models.TemplateExternalParameter)
 	templateExternalParameter.ID, _ = strconv.Atoi(id)
 
+	if err := validateTemplateExternalParameter(templateExternalParameter); err != nil {
+		return nil, err
+	}
+
 	if err := db.Save(&templateExternalParameter).Error; err != nil {
 		return nil, err
 	}
@@ -130,6 +139,10 @@ func (_ *TemplateLogic) Create(db *gorm.DB, data interface{}) (interface{}, erro
 		return nil, err
 	}
 
+	if err := recordTemplateRevision(db, template); err != nil {
+		return nil, err
+	}
+
 	return template, nil
 }
 
@@ -141,6 +154,10 @@ func (_ *TemplateLogic) Update(db *gorm.DB, id string, data interface{}) (interf
 		return nil, err
 	}
 
+	if err := recordTemplateRevision(db, template); err != nil {
+		return nil, err
+	}
+
 	return template, nil
 }
 
@@ -160,45 +177,95 @@ func (_ *TemplateLogic) Delete(db *gorm.DB, id string) error {
 
 }
 
-func (_ *TemplateLogic) Patch(db *gorm.DB, id string, _ string) (interface{}, error) {
-	templateParameter := map[string]interface{}{}
-
-	templateParameterGenerators := extension.GetTemplateParameterGenerators()
-	for _, generator := range templateParameterGenerators {
-		key, value, err := generator.GenerateTemplateParameter(db)
-		if err != nil {
-			return nil, err
-		}
-		templateParameter[key] = value
-	}
+func (this *TemplateLogic) Patch(db *gorm.DB, id string, _ string) (interface{}, error) {
+	return this.Render(db, id, nil, false)
+}
 
+// Render generates the text with the parameters, which override or add to the external parameters only in this call.
+// In strict mode, the parameters missing in the template are errors instead of "<no value>".
+func (_ *TemplateLogic) Render(db *gorm.DB, id string, parameters map[string]interface{}, strict bool) (string, error) {
 	template := &models.Template{}
 	template.ID, _ = strconv.Atoi(id)
 
 	if err := db.Preload("TemplateExternalParameters").Select("*").First(template, template.ID).Error; err != nil {
-		return nil, err
+		return "", err
+	}
+
+	return renderTemplate(db, template, parameters, strict)
+}
+
+func renderTemplate(db *gorm.DB, template *models.Template, parameters map[string]interface{}, strict bool) (string, error) {
+	generatedParameters, err := generateTemplateParameters(db)
+	if err != nil {
+		return "", err
+	}
+
+	parameterSets, err := templateParameterSets(db, template)
+	if err != nil {
+		return "", err
+	}
+
+	tpl, err := parseTemplate(db, template, strict)
+	if err != nil {
+		return "", err
+	}
+
+	return executeTemplate(tpl, template, parameterSets, generatedParameters, parameters)
+}
+
+func generateTemplateParameters(db *gorm.DB) (*TemplateParameters, error) {
+	return TemplateParameterCacheInstance.Get(db, generateTemplateParametersWithoutCache)
+}
+
+func generateTemplateParametersWithoutCache(db *gorm.DB) (*TemplateParameters, error) {
+	templateParameters := &TemplateParameters{
+		Parameters: map[string]interface{}{},
+		Sources:    map[string]string{},
+		Warnings:   []string{},
 	}
 
-	templateExternalParameterMap := make(map[string]string)
-	for _, templateExternalParameter := range template.TemplateExternalParameters {
-		templateExternalParameterMap[templateExternalParameter.Name] = templateExternalParameter.Value
+	templateParameterGenerators := extension.GetTemplateParameterGenerators()
+	for _, generator := range templateParameterGenerators {
+		key, value, err := generator.GenerateTemplateParameter(db)
+		if err != nil {
+			return nil, err
+		}
+		templateParameters.set(key, value, fmt.Sprintf("%T", generator))
 	}
 
-	templateParameter["TemplateExternalParameters"] = templateExternalParameterMap
+	return templateParameters, nil
+}
 
-	tpl := tplpkg.New("template")
+func parseTemplate(db *gorm.DB, template *models.Template, strict bool) (*tplpkg.Template, error) {
+	tpl := tplpkg.New(template.Name)
 	templateFuncMaps := extension.GetTemplateFuncMaps()
 	for _, templateFuncMap := range templateFuncMaps {
 		tpl = tpl.Funcs(templateFuncMap)
 	}
+	if strict {
+		tpl = tpl.Option("missingkey=error")
+	}
 	tpl, err := tpl.Parse(template.TemplateContent)
 	if err != nil {
+		return nil, newTemplateError(TemplateErrorPhaseParse, template.Name, err)
+	}
+	if err := loadTemplateIncludes(db, tpl); err != nil {
 		return nil, err
 	}
 
+	return tpl, nil
+}
+
+// executeTemplate doesn't modify the generated parameters, so they can be shared between templates executed in parallel.
+func executeTemplate(tpl *tplpkg.Template, template *models.Template, parameterSets []*models.ParameterSet, generatedParameters *TemplateParameters, parameters map[string]interface{}) (string, error) {
+	templateParameters, err := generatedParameters.withExternalParameters(template, parameterSets, parameters)
+	if err != nil {
+		return "", err
+	}
+
 	var doc bytes.Buffer
-	if tpl.Execute(&doc, templateParameter) != nil {
-		return nil, err
+	if err := tpl.Execute(&doc, templateParameters.Parameters); err != nil {
+		return "", newTemplateError(TemplateErrorPhaseExecute, template.Name, err)
 	}
 
 	result := doc.String()
@@ -206,30 +273,65 @@ func (_ *TemplateLogic) Patch(db *gorm.DB, id string, _ string) (interface{}, er
 	return result, nil
 }
 
+// Parameters returns the parameters which Patch passes to the template, with the sources of them.
+func (_ *TemplateLogic) Parameters(db *gorm.DB, id string) (*TemplateParameters, error) {
+	generatedParameters, err := generateTemplateParameters(db)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.Template{}
+	template.ID, _ = strconv.Atoi(id)
+
+	if err := db.Preload("TemplateExternalParameters").Select("*").First(template, template.ID).Error; err != nil {
+		return nil, err
+	}
+
+	parameterSets, err := templateParameterSets(db, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return generatedParameters.withExternalParameters(template, parameterSets, nil)
+}
+
 func (_ *TemplateLogic) Options(db *gorm.DB) error {
 	return nil
 }
 
-func (_ *TemplateExternalParameterLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+func (_ *TemplateExternalParameterLogic) GetDesignKey() string {
+	return "template_external_parameters"
+}
+
+func (_ *TemplateExternalParameterLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{
+		"template_id": TemplateLogicInstance.GetDesignKey(),
+	}
+}
+
+func (this *TemplateExternalParameterLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
 	templateExternalParameters := []*models.TemplateExternalParameter{}
 	if err := db.Select("*").Find(&templateExternalParameters).Error; err != nil {
 		return "", nil, err
 	}
-	return "template_external_parameters", templateExternalParameters, nil
+	return this.GetDesignKey(), templateExternalParameters, nil
 }
 
 func (_ *TemplateExternalParameterLogic) DeleteFromDesign(db *gorm.DB) error {
 	return db.Exec("delete from template_external_parameters;").Error
 }
 
-func (_ *TemplateExternalParameterLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+func (this *TemplateExternalParameterLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
 	container := []*models.TemplateExternalParameter{}
 	design := data.(*models.Design)
-	if value, exists := design.Content["template_external_parameters"]; exists {
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
 		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
 			return err
 		}
 		for _, templateExternalParameter := range container {
+			if err := validateTemplateExternalParameter(templateExternalParameter); err != nil {
+				return err
+			}
 			if err := db.Create(templateExternalParameter).Error; err != nil {
 				return err
 			}
@@ -238,22 +340,49 @@ func (_ *TemplateExternalParameterLogic) LoadToDesign(db *gorm.DB, data interfac
 	return nil
 }
 
-func (_ *TemplateLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+func (this *TemplateExternalParameterLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateExternalParameter{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateExternalParameter := range container {
+			if err := validateTemplateExternalParameter(templateExternalParameter); err != nil {
+				return err
+			}
+			if err := db.Save(templateExternalParameter).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (_ *TemplateLogic) GetDesignKey() string {
+	return "templates"
+}
+
+func (_ *TemplateLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{}
+}
+
+func (this *TemplateLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
 	templates := []*models.Template{}
 	if err := db.Select("*").Find(&templates).Error; err != nil {
 		return "", nil, err
 	}
-	return "templates", templates, nil
+	return this.GetDesignKey(), templates, nil
 }
 
 func (_ *TemplateLogic) DeleteFromDesign(db *gorm.DB) error {
 	return db.Exec("delete from templates;").Error
 }
 
-func (_ *TemplateLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+func (this *TemplateLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
 	container := []*models.Template{}
 	design := data.(*models.Design)
-	if value, exists := design.Content["templates"]; exists {
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
 		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
 			return err
 		}
@@ -267,6 +396,23 @@ func (_ *TemplateLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
 	return nil
 }
 
+func (this *TemplateLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.Template{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, template := range container {
+			template.TemplateExternalParameters = nil
+			if err := db.Save(template).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 var TemplateExternalParameterLogicInstance = &TemplateExternalParameterLogic{}
 var TemplateLogicInstance = &TemplateLogic{}
 
@@ -0,0 +1,121 @@
+package logics
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/helper"
+	"github.com/qb0C80aE/clay/models"
+	"sync"
+	tplpkg "text/template"
+)
+
+const templateArchiveManifest = "manifest.yaml"
+
+type TemplateRenderResult struct {
+	ID     int            `json:"id"`
+	Name   string         `json:"name"`
+	File   string         `json:"file,omitempty"`
+	Error  string         `json:"error,omitempty"`
+	Detail *TemplateError `json:"detail,omitempty"`
+}
+
+type TemplateArchiveManifest struct {
+	Templates []*TemplateRenderResult `json:"templates"`
+}
+
+func (this *TemplateRenderResult) setError(err error) {
+	this.Error = err.Error()
+	if templateError, ok := err.(*TemplateError); ok {
+		this.Detail = templateError
+	}
+}
+
+// RenderArchive renders the templates into an archive, with the manifest which reports the file or the error of every template.
+// The parameters are generated only once, and the templates are executed in parallel.
+func (_ *TemplateLogic) RenderArchive(db *gorm.DB, templates []*models.Template, archive string, strict bool) ([]byte, error) {
+	if err := validateArchive(archive); err != nil {
+		return nil, err
+	}
+
+	generatedParameters, err := generateTemplateParameters(db)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*TemplateRenderResult, len(templates))
+	tpls := make([]*tplpkg.Template, len(templates))
+	loadedTemplates := make([]*models.Template, len(templates))
+	loadedParameterSets := make([][]*models.ParameterSet, len(templates))
+
+	// Parsing loads the includes from the database, so only the execution runs in parallel.
+	for i, template := range templates {
+		results[i] = &TemplateRenderResult{
+			ID:   template.ID,
+			Name: template.Name,
+		}
+
+		loadedTemplate := &models.Template{}
+		if err := db.Preload("TemplateExternalParameters").Select("*").First(loadedTemplate, template.ID).Error; err != nil {
+			return nil, err
+		}
+		loadedTemplates[i] = loadedTemplate
+
+		parameterSets, err := templateParameterSets(db, loadedTemplate)
+		if err != nil {
+			return nil, err
+		}
+		loadedParameterSets[i] = parameterSets
+
+		tpl, err := parseTemplate(db, loadedTemplate, strict)
+		if err != nil {
+			results[i].setError(err)
+			continue
+		}
+		tpls[i] = tpl
+	}
+
+	outputs := make([]string, len(templates))
+	waitGroup := &sync.WaitGroup{}
+	for i := range templates {
+		if tpls[i] == nil {
+			continue
+		}
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			output, err := executeTemplate(tpls[i], loadedTemplates[i], loadedParameterSets[i], generatedParameters, nil)
+			if err != nil {
+				results[i].setError(err)
+				return
+			}
+			outputs[i] = output
+		}(i)
+	}
+	waitGroup.Wait()
+
+	files := map[string][]byte{}
+	paths := []string{templateArchiveManifest}
+	for i, result := range results {
+		if result.Error != "" {
+			continue
+		}
+
+		filePath := archiveFileName(result.Name)
+		if _, exists := files[filePath]; exists || filePath == templateArchiveManifest {
+			filePath = fmt.Sprintf("%s-%d", filePath, result.ID)
+		}
+		files[filePath] = []byte(outputs[i])
+		paths = append(paths, filePath)
+		result.File = filePath
+	}
+
+	manifest := &TemplateArchiveManifest{
+		Templates: results,
+	}
+	files[templateArchiveManifest], err = helper.MarshalYAML(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeArchive(archive, paths, files)
+}
@@ -0,0 +1,72 @@
+package logics
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+const (
+	TemplateErrorPhaseParse   = "parse"
+	TemplateErrorPhaseExecute = "execute"
+)
+
+type TemplateError struct {
+	Phase    string `json:"phase"`
+	Template string `json:"template"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Message  string `json:"message"`
+}
+
+// text/template reports the position as "template: <name>:<line>[:<column>]: <message>",
+// and the failing action as "executing \"<name>\" at <<action>>: <message>" in execution errors.
+var templateErrorPattern = regexp.MustCompile(`^template: (.*?):(\d+):(?:(\d+):)? (.*)$`)
+var templateExecuteErrorPattern = regexp.MustCompile(`^executing ".*?" at <(.*?)>: (.*)$`)
+
+func newTemplateError(phase string, name string, err error) *TemplateError {
+	templateError := &TemplateError{
+		Phase:    phase,
+		Template: name,
+		Message:  err.Error(),
+	}
+
+	matches := templateErrorPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return templateError
+	}
+
+	templateError.Line, _ = strconv.Atoi(matches[2])
+	templateError.Column, _ = strconv.Atoi(matches[3])
+	templateError.Message = matches[4]
+
+	if executeMatches := templateExecuteErrorPattern.FindStringSubmatch(templateError.Message); executeMatches != nil {
+		templateError.Action = executeMatches[1]
+		templateError.Message = executeMatches[2]
+	}
+
+	return templateError
+}
+
+func (this *TemplateError) Error() string {
+	position := fmt.Sprintf("line %d", this.Line)
+	if this.Column > 0 {
+		position = fmt.Sprintf("%s, column %d", position, this.Column)
+	}
+	if this.Action != "" {
+		position = fmt.Sprintf("%s in <%s>", position, this.Action)
+	}
+	return fmt.Sprintf("Invalid Template. Failed to %s %s at %s: %s", this.Phase, this.Template, position, this.Message)
+}
+
+func (this *TemplateError) GetStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (this *TemplateError) GetErrorDetails() map[string]interface{} {
+	return map[string]interface{}{
+		"detail": this,
+	}
+}
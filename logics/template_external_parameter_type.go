@@ -0,0 +1,46 @@
+package logics
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/qb0C80aE/clay/models"
+	"strconv"
+)
+
+func decodeTemplateExternalParameter(templateExternalParameter *models.TemplateExternalParameter) (interface{}, error) {
+	switch templateExternalParameter.Type {
+	case "", models.TemplateExternalParameterTypeString:
+		return templateExternalParameter.Value, nil
+	case models.TemplateExternalParameterTypeInt:
+		value, err := strconv.ParseInt(templateExternalParameter.Value, 10, 64)
+		if err != nil {
+			return nil, invalidTemplateExternalParameterValueError(templateExternalParameter)
+		}
+		return value, nil
+	case models.TemplateExternalParameterTypeBool:
+		value, err := strconv.ParseBool(templateExternalParameter.Value)
+		if err != nil {
+			return nil, invalidTemplateExternalParameterValueError(templateExternalParameter)
+		}
+		return value, nil
+	case models.TemplateExternalParameterTypeJSON:
+		var value interface{}
+		if err := json.Unmarshal([]byte(templateExternalParameter.Value), &value); err != nil {
+			return nil, invalidTemplateExternalParameterValueError(templateExternalParameter)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("Invalid Parameter. type of %s must be string, int, bool or json, but '%s'.",
+			templateExternalParameter.Name, templateExternalParameter.Type)
+	}
+}
+
+func invalidTemplateExternalParameterValueError(templateExternalParameter *models.TemplateExternalParameter) error {
+	return fmt.Errorf("Invalid Parameter. value of %s is not a valid %s, '%s'.",
+		templateExternalParameter.Name, templateExternalParameter.Type, templateExternalParameter.Value)
+}
+
+func validateTemplateExternalParameter(templateExternalParameter *models.TemplateExternalParameter) error {
+	_, err := decodeTemplateExternalParameter(templateExternalParameter)
+	return err
+}
@@ -0,0 +1,404 @@
+package logics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/helper"
+	netutil "github.com/qb0C80aE/clay/utils/net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	tplpkg "text/template"
+)
+
+// The limits keep a template from exhausting the memory of the server.
+const (
+	templateFuncMaxCount  = 10000
+	templateFuncMaxLength = 10000
+)
+
+// The arguments piped into the functions come last, like {{.hostname | replace "-" "_"}}.
+var templateFuncMap = tplpkg.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+	"replace":    func(old string, new string, s string) string { return strings.Replace(s, old, new, -1) },
+	"repeat":     templateRepeat,
+	"split":      func(separator string, s string) []string { return strings.Split(s, separator) },
+	"join":       templateJoin,
+	"indent":     templateIndent,
+	"quote":      strconv.Quote,
+
+	"add":   templateAdd,
+	"sub":   templateSub,
+	"mul":   templateMul,
+	"div":   templateDiv,
+	"mod":   templateMod,
+	"max":   templateMax,
+	"min":   templateMin,
+	"int":   templateInt,
+	"until": templateUntil,
+
+	"list":   func(items ...interface{}) []interface{} { return items },
+	"dict":   templateDict,
+	"first":  templateFirst,
+	"last":   templateLast,
+	"append": templateAppend,
+	"keys":   templateKeys,
+	"hasKey": templateHasKey,
+
+	"toJSON":       templateToJSON,
+	"toPrettyJSON": templateToPrettyJSON,
+	"fromJSON":     templateFromJSON,
+	"toYAML":       templateToYAML,
+	"fromYAML":     templateFromYAML,
+
+	"regexMatch":      func(pattern string, s string) (bool, error) { return regexp.MatchString(pattern, s) },
+	"regexFind":       templateRegexFind,
+	"regexFindAll":    templateRegexFindAll,
+	"regexReplaceAll": templateRegexReplaceAll,
+
+	"default":  templateDefault,
+	"required": templateRequired,
+	"empty":    templateEmpty,
+	"coalesce": templateCoalesce,
+
+	"ParseCIDR":              netutil.ParseCIDR,
+	"IncreaseHostAddress":    netutil.IncreaseHostAddress,
+	"DecreaseHostAddress":    netutil.DecreaseHostAddress,
+	"IncreaseNetworkAddress": netutil.IncreaseNetworkAddress,
+	"DecreaseNetworkAddress": netutil.DecreaseNetworkAddress,
+	"IncreaseIpAddress":      netutil.IncreaseIpAddress,
+	"DecreaseIpAddress":      netutil.DecreaseIpAddress,
+	"LimitedBroadcast":       netutil.LimitedBroadcast,
+	"Network":                netutil.Network,
+	"MaxHost":                netutil.MaxHost,
+	"MinimumHost":            netutil.MinimumHost,
+	"IsBroadcastAddress":     netutil.IsBroadcastAddress,
+	"IsNetworkAddress":       netutil.IsNetworkAddress,
+	"String":                 netutil.String,
+	"StringWithPrefix":       netutil.StringWithPrefix,
+}
+
+func templateInt(value interface{}) (int64, error) {
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflectValue.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(reflectValue.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return int64(reflectValue.Float()), nil
+	case reflect.String:
+		result, err := strconv.ParseInt(strings.TrimSpace(reflectValue.String()), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer.", reflectValue.String())
+		}
+		return result, nil
+	default:
+		return 0, fmt.Errorf("%v is not an integer.", value)
+	}
+}
+
+func templateArithmetic(a interface{}, b interface{}, operation func(int64, int64) int64) (int64, error) {
+	x, err := templateInt(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := templateInt(b)
+	if err != nil {
+		return 0, err
+	}
+	return operation(x, y), nil
+}
+
+func templateAdd(values ...interface{}) (int64, error) {
+	result := int64(0)
+	for _, value := range values {
+		x, err := templateInt(value)
+		if err != nil {
+			return 0, err
+		}
+		result += x
+	}
+	return result, nil
+}
+
+func templateSub(a interface{}, b interface{}) (int64, error) {
+	return templateArithmetic(a, b, func(x, y int64) int64 { return x - y })
+}
+
+func templateMul(a interface{}, b interface{}) (int64, error) {
+	return templateArithmetic(a, b, func(x, y int64) int64 { return x * y })
+}
+
+func templateMax(a interface{}, b interface{}) (int64, error) {
+	return templateArithmetic(a, b, func(x, y int64) int64 {
+		if x > y {
+			return x
+		}
+		return y
+	})
+}
+
+func templateMin(a interface{}, b interface{}) (int64, error) {
+	return templateArithmetic(a, b, func(x, y int64) int64 {
+		if x < y {
+			return x
+		}
+		return y
+	})
+}
+
+func templateDiv(a interface{}, b interface{}) (int64, error) {
+	y, err := templateInt(b)
+	if err != nil {
+		return 0, err
+	}
+	if y == 0 {
+		return 0, errors.New("division by zero.")
+	}
+	return templateArithmetic(a, y, func(x, y int64) int64 { return x / y })
+}
+
+func templateMod(a interface{}, b interface{}) (int64, error) {
+	y, err := templateInt(b)
+	if err != nil {
+		return 0, err
+	}
+	if y == 0 {
+		return 0, errors.New("division by zero.")
+	}
+	return templateArithmetic(a, y, func(x, y int64) int64 { return x % y })
+}
+
+func templateCount(count int64) error {
+	if count < 0 || count > templateFuncMaxCount {
+		return fmt.Errorf("%d is out of the range from 0 to %d.", count, templateFuncMaxCount)
+	}
+	return nil
+}
+
+func templateUntil(count interface{}) ([]int64, error) {
+	n, err := templateInt(count)
+	if err != nil {
+		return nil, err
+	}
+	if err := templateCount(n); err != nil {
+		return nil, err
+	}
+	result := []int64{}
+	for i := int64(0); i < n; i++ {
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func templateList(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{}, nil
+	}
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%v is not a list.", value)
+	}
+	result := make([]interface{}, reflectValue.Len())
+	for i := range result {
+		result[i] = reflectValue.Index(i).Interface()
+	}
+	return result, nil
+}
+
+func templateJoin(separator string, value interface{}) (string, error) {
+	items, err := templateList(value)
+	if err != nil {
+		return "", err
+	}
+	texts := make([]string, len(items))
+	for i, item := range items {
+		texts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(texts, separator), nil
+}
+
+func templateRepeat(count int, s string) (string, error) {
+	if err := templateCount(int64(count)); err != nil {
+		return "", err
+	}
+	if len(s)*count > templateFuncMaxLength {
+		return "", fmt.Errorf("the result is longer than %d bytes.", templateFuncMaxLength)
+	}
+	return strings.Repeat(s, count), nil
+}
+
+func templateIndent(width int, s string) (string, error) {
+	if err := templateCount(int64(width)); err != nil {
+		return "", err
+	}
+	padding := strings.Repeat(" ", width)
+	return padding + strings.Replace(s, "\n", "\n"+padding, -1), nil
+}
+
+func templateDict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict needs pairs of a key and a value.")
+	}
+	result := map[string]interface{}{}
+	for i := 0; i < len(pairs); i += 2 {
+		result[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return result, nil
+}
+
+func templateFirst(value interface{}) (interface{}, error) {
+	items, err := templateList(value)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+func templateLast(value interface{}) (interface{}, error) {
+	items, err := templateList(value)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[len(items)-1], nil
+}
+
+func templateAppend(value interface{}, item interface{}) ([]interface{}, error) {
+	items, err := templateList(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, item), nil
+}
+
+func templateKeys(value interface{}) ([]string, error) {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Map {
+		return nil, fmt.Errorf("%v is not a dict.", value)
+	}
+	result := []string{}
+	for _, key := range reflectValue.MapKeys() {
+		result = append(result, fmt.Sprint(key.Interface()))
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func templateHasKey(value interface{}, key string) (bool, error) {
+	keys, err := templateKeys(value)
+	if err != nil {
+		return false, err
+	}
+	for _, existingKey := range keys {
+		if existingKey == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func templateToJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+func templateToPrettyJSON(value interface{}) (string, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	return string(data), err
+}
+
+func templateFromJSON(s string) (interface{}, error) {
+	var result interface{}
+	err := json.Unmarshal([]byte(s), &result)
+	return result, err
+}
+
+func templateToYAML(value interface{}) (string, error) {
+	data, err := helper.MarshalYAML(value)
+	return string(data), err
+}
+
+func templateFromYAML(s string) (interface{}, error) {
+	var result interface{}
+	err := helper.UnmarshalYAML([]byte(s), &result)
+	return result, err
+}
+
+func templateRegexFind(pattern string, s string) (string, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return expression.FindString(s), nil
+}
+
+func templateRegexFindAll(pattern string, s string) ([]string, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return expression.FindAllString(s, -1), nil
+}
+
+func templateRegexReplaceAll(pattern string, replacement string, s string) (string, error) {
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return expression.ReplaceAllString(s, replacement), nil
+}
+
+func templateEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return reflectValue.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return reflectValue.IsNil()
+	default:
+		return reflect.DeepEqual(value, reflect.Zero(reflectValue.Type()).Interface())
+	}
+}
+
+func templateDefault(defaultValue interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || templateEmpty(value[0]) {
+		return defaultValue
+	}
+	return value[0]
+}
+
+func templateRequired(message string, value ...interface{}) (interface{}, error) {
+	if len(value) == 0 || templateEmpty(value[0]) {
+		return nil, errors.New(message)
+	}
+	return value[0], nil
+}
+
+func templateCoalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !templateEmpty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+func init() {
+	extension.RegisterTemplateFuncMap(templateFuncMap)
+}
@@ -0,0 +1,169 @@
+package logics
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	tplpkg "text/template"
+	"text/template/parse"
+)
+
+const TemplateErrorPhaseInclude = "include"
+
+type templateInclude struct {
+	tree *parse.Tree
+	node *parse.TemplateNode
+}
+
+var templateLocationPattern = regexp.MustCompile(`^.*:(\d+):(\d+)$`)
+
+func (this *templateInclude) error(message string) *TemplateError {
+	location, context := this.tree.ErrorContext(this.node)
+
+	templateError := &TemplateError{
+		Phase:    TemplateErrorPhaseInclude,
+		Template: this.tree.ParseName,
+		Action:   strings.TrimSuffix(strings.TrimPrefix(context, "{{"), "}}"),
+		Message:  message,
+	}
+	if matches := templateLocationPattern.FindStringSubmatch(location); matches != nil {
+		templateError.Line, _ = strconv.Atoi(matches[1])
+		templateError.Column, _ = strconv.Atoi(matches[2])
+	}
+
+	return templateError
+}
+
+func walkTemplateIncludes(tree *parse.Tree, node parse.Node, includes []*templateInclude) []*templateInclude {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return includes
+		}
+		for _, child := range node.Nodes {
+			includes = walkTemplateIncludes(tree, child, includes)
+		}
+	case *parse.IfNode:
+		includes = walkTemplateIncludes(tree, node.List, includes)
+		includes = walkTemplateIncludes(tree, node.ElseList, includes)
+	case *parse.RangeNode:
+		includes = walkTemplateIncludes(tree, node.List, includes)
+		includes = walkTemplateIncludes(tree, node.ElseList, includes)
+	case *parse.WithNode:
+		includes = walkTemplateIncludes(tree, node.List, includes)
+		includes = walkTemplateIncludes(tree, node.ElseList, includes)
+	case *parse.TemplateNode:
+		includes = append(includes, &templateInclude{
+			tree: tree,
+			node: node,
+		})
+	}
+	return includes
+}
+
+// templateIncludes returns the includes of every template in the set, ordered by the template names.
+func templateIncludes(tpl *tplpkg.Template) map[string][]*templateInclude {
+	result := map[string][]*templateInclude{}
+	for _, associated := range tpl.Templates() {
+		if associated.Tree == nil {
+			continue
+		}
+		result[associated.Name()] = walkTemplateIncludes(associated.Tree, associated.Tree.Root, []*templateInclude{})
+	}
+	return result
+}
+
+func sortedTemplateNames(includes map[string][]*templateInclude) []string {
+	names := []string{}
+	for name := range includes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTemplateIncludes parses the stored templates which are included from the set by their names, until nothing is missing.
+func loadTemplateIncludes(db *gorm.DB, tpl *tplpkg.Template) error {
+	for loaded := true; loaded; {
+		loaded = false
+
+		includes := templateIncludes(tpl)
+		for _, name := range sortedTemplateNames(includes) {
+			for _, include := range includes[name] {
+				if tpl.Lookup(include.node.Name) != nil {
+					continue
+				}
+
+				template := &models.Template{}
+				if err := db.Where("name = ?", include.node.Name).Order("id").First(template).Error; err != nil {
+					if err == gorm.ErrRecordNotFound {
+						return include.error(fmt.Sprintf("template %q does not exist.", include.node.Name))
+					}
+					return err
+				}
+
+				if _, err := tpl.New(template.Name).Parse(template.TemplateContent); err != nil {
+					return newTemplateError(TemplateErrorPhaseParse, template.Name, err)
+				}
+				loaded = true
+			}
+		}
+	}
+
+	return checkTemplateIncludeCycles(tpl)
+}
+
+func checkTemplateIncludeCycles(tpl *tplpkg.Template) error {
+	includes := templateIncludes(tpl)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := map[string]int{}
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		states[name] = visiting
+		path = append(path, name)
+
+		for _, include := range includes[name] {
+			switch states[include.node.Name] {
+			case visiting:
+				cycle := []string{}
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == include.node.Name {
+						cycle = append(cycle, path[i:]...)
+						break
+					}
+				}
+				cycle = append(cycle, include.node.Name)
+				return include.error(fmt.Sprintf("the includes form a cycle, %s.", strings.Join(cycle, " -> ")))
+			case unvisited:
+				if err := visit(include.node.Name); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		states[name] = visited
+		return nil
+	}
+
+	for _, name := range sortedTemplateNames(includes) {
+		if states[name] == unvisited {
+			if err := visit(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package logics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/models"
+	"github.com/qb0C80aE/clay/utils/mapstruct"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TemplateOutputRootEnv         = "TEMPLATE_OUTPUT_ROOT"
+	TemplateOutputRootDefault     = "output"
+	TemplateOutputWebhookHostsEnv = "TEMPLATE_OUTPUT_WEBHOOK_HOSTS"
+)
+
+const templateOutputWebhookTimeout = 30 * time.Second
+
+type TemplateOutputTargetLogic struct {
+}
+
+func (_ *TemplateOutputTargetLogic) GetSingle(db *gorm.DB, id string, queryFields string) (interface{}, error) {
+
+	templateOutputTarget := &models.TemplateOutputTarget{}
+
+	if err := db.Select(queryFields).First(templateOutputTarget, id).Error; err != nil {
+		return nil, err
+	}
+
+	return templateOutputTarget, nil
+
+}
+
+func (_ *TemplateOutputTargetLogic) GetMulti(db *gorm.DB, queryFields string) ([]interface{}, error) {
+
+	templateOutputTargets := []*models.TemplateOutputTarget{}
+
+	if err := db.Select(queryFields).Find(&templateOutputTargets).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(templateOutputTargets))
+	for i, data := range templateOutputTargets {
+		result[i] = data
+	}
+
+	return result, nil
+
+}
+
+func (_ *TemplateOutputTargetLogic) Create(db *gorm.DB, data interface{}) (interface{}, error) {
+
+	templateOutputTarget := data.(*models.TemplateOutputTarget)
+	templateOutputTarget.Status = ""
+	templateOutputTarget.Error = ""
+	templateOutputTarget.PublishedAt = nil
+
+	if err := validateTemplateOutputTarget(templateOutputTarget); err != nil {
+		return nil, err
+	}
+
+	if err := db.Create(templateOutputTarget).Error; err != nil {
+		return nil, err
+	}
+
+	return templateOutputTarget, nil
+
+}
+
+func (_ *TemplateOutputTargetLogic) Update(db *gorm.DB, id string, data interface{}) (interface{}, error) {
+
+	templateOutputTarget := data.(*models.TemplateOutputTarget)
+	templateOutputTarget.ID, _ = strconv.Atoi(id)
+
+	// The result of the last publish is kept, since only Publish records it.
+	existingTemplateOutputTarget := &models.TemplateOutputTarget{}
+	if err := db.First(existingTemplateOutputTarget, templateOutputTarget.ID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	templateOutputTarget.Status = existingTemplateOutputTarget.Status
+	templateOutputTarget.Error = existingTemplateOutputTarget.Error
+	templateOutputTarget.PublishedAt = existingTemplateOutputTarget.PublishedAt
+
+	if err := validateTemplateOutputTarget(templateOutputTarget); err != nil {
+		return nil, err
+	}
+
+	if err := db.Save(templateOutputTarget).Error; err != nil {
+		return nil, err
+	}
+
+	return templateOutputTarget, nil
+
+}
+
+func (_ *TemplateOutputTargetLogic) Delete(db *gorm.DB, id string) error {
+
+	templateOutputTarget := &models.TemplateOutputTarget{}
+
+	if err := db.First(&templateOutputTarget, id).Error; err != nil {
+		return err
+	}
+
+	if err := db.Delete(&templateOutputTarget).Error; err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func (_ *TemplateOutputTargetLogic) Patch(_ *gorm.DB, _ string, _ string) (interface{}, error) {
+	return nil, nil
+}
+
+func (_ *TemplateOutputTargetLogic) Options(db *gorm.DB) error {
+	return nil
+}
+
+func validateTemplateOutputTarget(templateOutputTarget *models.TemplateOutputTarget) error {
+	switch templateOutputTarget.Type {
+	case models.TemplateOutputTargetTypeFile:
+		if _, err := templateOutputFilePath(templateOutputTarget.Destination); err != nil {
+			return err
+		}
+	case models.TemplateOutputTargetTypeWebhook:
+		destination, err := url.Parse(templateOutputTarget.Destination)
+		if err != nil || (destination.Scheme != "http" && destination.Scheme != "https") || destination.Host == "" {
+			return fmt.Errorf("Invalid Parameter. destination of a webhook target must be an http or https URL, but '%s'.", templateOutputTarget.Destination)
+		}
+		if err := validateTemplateOutputWebhookHost(destination); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Invalid Parameter. type of an output target must be file or webhook, but '%s'.", templateOutputTarget.Type)
+	}
+	return nil
+}
+
+// validateTemplateOutputWebhookHost accepts only the hosts listed in TEMPLATE_OUTPUT_WEBHOOK_HOSTS, so webhooks can't reach arbitrary servers.
+// The entries are host names, or host names with ports.
+func validateTemplateOutputWebhookHost(destination *url.URL) error {
+	hostname := destination.Host
+	if host, _, err := net.SplitHostPort(destination.Host); err == nil {
+		hostname = host
+	}
+	hostname = strings.Trim(hostname, "[]")
+
+	for _, allowedHost := range strings.Split(os.Getenv(TemplateOutputWebhookHostsEnv), ",") {
+		allowedHost = strings.TrimSpace(allowedHost)
+		if allowedHost != "" && (strings.EqualFold(allowedHost, destination.Host) || strings.EqualFold(allowedHost, hostname)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Invalid Parameter. host of a webhook target must be one of %s, but '%s'.", TemplateOutputWebhookHostsEnv, destination.Host)
+}
+
+// templateOutputFilePath resolves the destination of a file target under the output root, which it must not escape.
+// Symbolic links are resolved before the check, so links under the root can't point outside of it either.
+func templateOutputFilePath(destination string) (string, error) {
+	cleaned := filepath.Clean(destination)
+	if destination == "" || filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("Invalid Parameter. destination of a file target must be a relative path under the output root, but '%s'.", destination)
+	}
+
+	root := os.Getenv(TemplateOutputRootEnv)
+	if root == "" {
+		root = TemplateOutputRootDefault
+	}
+
+	resolvedRoot, err := resolveExistingPath(root)
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err := resolveExistingPath(filepath.Join(root, cleaned))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(resolvedPath, resolvedRoot+string(filepath.Separator)) {
+		return "", fmt.Errorf("Invalid Parameter. destination of a file target must be a relative path under the output root, but '%s'.", destination)
+	}
+
+	return resolvedPath, nil
+}
+
+// resolveExistingPath resolves the symbolic links in the longest existing part of the path, and appends the rest as is.
+func resolveExistingPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rest := ""
+	for {
+		resolvedPath, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolvedPath, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(path, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+func deliverTemplateOutput(templateOutputTarget *models.TemplateOutputTarget, output string) error {
+	switch templateOutputTarget.Type {
+	case models.TemplateOutputTargetTypeFile:
+		path, err := templateOutputFilePath(templateOutputTarget.Destination)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, []byte(output), 0644)
+	case models.TemplateOutputTargetTypeWebhook:
+		if err := validateTemplateOutputTarget(templateOutputTarget); err != nil {
+			return err
+		}
+		client := &http.Client{
+			Timeout: templateOutputWebhookTimeout,
+			CheckRedirect: func(request *http.Request, _ []*http.Request) error {
+				return validateTemplateOutputWebhookHost(request.URL)
+			},
+		}
+		response, err := client.Post(templateOutputTarget.Destination, "text/plain; charset=utf-8", bytes.NewBufferString(output))
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return fmt.Errorf("webhook responded %s.", response.Status)
+		}
+		return nil
+	default:
+		return validateTemplateOutputTarget(templateOutputTarget)
+	}
+}
+
+// Publish renders the template and delivers the text to every output target of it.
+// The failures of the delivery don't stop the others, and are recorded in the targets instead of being returned.
+func (this *TemplateLogic) Publish(db *gorm.DB, id string, parameters map[string]interface{}, strict bool) ([]*models.TemplateOutputTarget, error) {
+	output, err := this.Render(db, id, parameters, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	templateID, _ := strconv.Atoi(id)
+	templateOutputTargets := []*models.TemplateOutputTarget{}
+	if err := db.Where(&models.TemplateOutputTarget{TemplateID: templateID}).Order("id").Find(&templateOutputTargets).Error; err != nil {
+		return nil, err
+	}
+
+	for _, templateOutputTarget := range templateOutputTargets {
+		publishedAt := time.Now().UTC()
+		templateOutputTarget.PublishedAt = &publishedAt
+		if err := deliverTemplateOutput(templateOutputTarget, output); err != nil {
+			templateOutputTarget.Status = models.TemplateOutputTargetStatusFailed
+			templateOutputTarget.Error = err.Error()
+		} else {
+			templateOutputTarget.Status = models.TemplateOutputTargetStatusSucceeded
+			templateOutputTarget.Error = ""
+		}
+		if err := db.Save(templateOutputTarget).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return templateOutputTargets, nil
+}
+
+func (_ *TemplateOutputTargetLogic) GetDesignKey() string {
+	return "template_output_targets"
+}
+
+func (_ *TemplateOutputTargetLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{
+		"template_id": TemplateLogicInstance.GetDesignKey(),
+	}
+}
+
+func (this *TemplateOutputTargetLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+	templateOutputTargets := []*models.TemplateOutputTarget{}
+	if err := db.Select("*").Find(&templateOutputTargets).Error; err != nil {
+		return "", nil, err
+	}
+	return this.GetDesignKey(), templateOutputTargets, nil
+}
+
+func (_ *TemplateOutputTargetLogic) DeleteFromDesign(db *gorm.DB) error {
+	return db.Exec("delete from template_output_targets;").Error
+}
+
+func (this *TemplateOutputTargetLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateOutputTarget{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateOutputTarget := range container {
+			if err := validateTemplateOutputTarget(templateOutputTarget); err != nil {
+				return err
+			}
+			if err := db.Create(templateOutputTarget).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *TemplateOutputTargetLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateOutputTarget{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateOutputTarget := range container {
+			if err := validateTemplateOutputTarget(templateOutputTarget); err != nil {
+				return err
+			}
+			if err := db.Save(templateOutputTarget).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var TemplateOutputTargetLogicInstance = &TemplateOutputTargetLogic{}
+
+func init() {
+	extension.RegisterDesignAccessor(TemplateOutputTargetLogicInstance)
+}
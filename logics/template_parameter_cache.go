@@ -0,0 +1,87 @@
+package logics
+
+import (
+	"database/sql"
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+	"sync"
+)
+
+type TemplateParameterCacheStats struct {
+	Cached        bool   `json:"cached"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// TemplateParameterCache keeps the parameters generated by the TemplateParameterGenerators until any model changes.
+type TemplateParameterCache struct {
+	mutex      sync.Mutex
+	database   *sql.DB
+	parameters *TemplateParameters
+	generation uint64
+	stats      TemplateParameterCacheStats
+}
+
+func (this *TemplateParameterCache) Get(db *gorm.DB, generate func(*gorm.DB) (*TemplateParameters, error)) (*TemplateParameters, error) {
+	// Transactions may contain changes which are not committed yet, so they always generate the parameters.
+	database, ok := db.CommonDB().(*sql.DB)
+	if !ok {
+		return generate(db)
+	}
+
+	this.mutex.Lock()
+	if this.parameters != nil && this.database == database {
+		this.stats.Hits++
+		parameters := this.parameters
+		this.mutex.Unlock()
+		return parameters, nil
+	}
+	this.stats.Misses++
+	generation := this.generation
+	this.mutex.Unlock()
+
+	parameters, err := generate(db)
+	if err != nil {
+		return nil, err
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	// The parameters generated while the cache is invalidated may be already stale.
+	if this.generation == generation {
+		this.database = database
+		this.parameters = parameters
+	}
+
+	return parameters, nil
+}
+
+func (this *TemplateParameterCache) Invalidate() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.generation++
+	this.database = nil
+	this.parameters = nil
+	this.stats.Invalidations++
+}
+
+func (this *TemplateParameterCache) ModelChanged() {
+	this.Invalidate()
+}
+
+func (this *TemplateParameterCache) Stats() *TemplateParameterCacheStats {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	stats := this.stats
+	stats.Cached = this.parameters != nil
+	return &stats
+}
+
+var TemplateParameterCacheInstance = &TemplateParameterCache{}
+
+func init() {
+	extension.RegisterModelChangedHook(TemplateParameterCacheInstance)
+}
@@ -0,0 +1,332 @@
+package logics
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/models"
+	"github.com/qb0C80aE/clay/utils/mapstruct"
+	"sort"
+	"strconv"
+)
+
+type ParameterSetLogic struct {
+}
+
+type TemplateParameterSetLogic struct {
+}
+
+type parameterSetsByPriority []*models.ParameterSet
+
+func (this parameterSetsByPriority) Len() int {
+	return len(this)
+}
+
+func (this parameterSetsByPriority) Less(i, j int) bool {
+	if this[i].Priority != this[j].Priority {
+		return this[i].Priority < this[j].Priority
+	}
+	return this[i].ID < this[j].ID
+}
+
+func (this parameterSetsByPriority) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+}
+
+func (_ *ParameterSetLogic) GetSingle(db *gorm.DB, id string, queryFields string) (interface{}, error) {
+
+	parameterSet := &models.ParameterSet{}
+
+	if err := db.Select(queryFields).First(parameterSet, id).Error; err != nil {
+		return nil, err
+	}
+
+	return parameterSet, nil
+
+}
+
+func (_ *ParameterSetLogic) GetMulti(db *gorm.DB, queryFields string) ([]interface{}, error) {
+
+	parameterSets := []*models.ParameterSet{}
+
+	if err := db.Select(queryFields).Find(&parameterSets).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(parameterSets))
+	for i, data := range parameterSets {
+		result[i] = data
+	}
+
+	return result, nil
+
+}
+
+func (_ *ParameterSetLogic) Create(db *gorm.DB, data interface{}) (interface{}, error) {
+
+	parameterSet := data.(*models.ParameterSet)
+
+	if err := db.Create(parameterSet).Error; err != nil {
+		return nil, err
+	}
+
+	return parameterSet, nil
+
+}
+
+func (_ *ParameterSetLogic) Update(db *gorm.DB, id string, data interface{}) (interface{}, error) {
+
+	parameterSet := data.(*models.ParameterSet)
+	parameterSet.ID, _ = strconv.Atoi(id)
+
+	if err := db.Save(parameterSet).Error; err != nil {
+		return nil, err
+	}
+
+	return parameterSet, nil
+
+}
+
+func (_ *ParameterSetLogic) Delete(db *gorm.DB, id string) error {
+
+	parameterSet := &models.ParameterSet{}
+
+	if err := db.First(&parameterSet, id).Error; err != nil {
+		return err
+	}
+
+	if err := db.Delete(&parameterSet).Error; err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func (_ *ParameterSetLogic) Patch(_ *gorm.DB, _ string, _ string) (interface{}, error) {
+	return nil, nil
+}
+
+func (_ *ParameterSetLogic) Options(db *gorm.DB) error {
+	return nil
+}
+
+func (_ *TemplateParameterSetLogic) GetSingle(db *gorm.DB, id string, queryFields string) (interface{}, error) {
+
+	templateParameterSet := &models.TemplateParameterSet{}
+
+	if err := db.Select(queryFields).First(templateParameterSet, id).Error; err != nil {
+		return nil, err
+	}
+
+	return templateParameterSet, nil
+
+}
+
+func (_ *TemplateParameterSetLogic) GetMulti(db *gorm.DB, queryFields string) ([]interface{}, error) {
+
+	templateParameterSets := []*models.TemplateParameterSet{}
+
+	if err := db.Select(queryFields).Find(&templateParameterSets).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, len(templateParameterSets))
+	for i, data := range templateParameterSets {
+		result[i] = data
+	}
+
+	return result, nil
+
+}
+
+func (_ *TemplateParameterSetLogic) Create(db *gorm.DB, data interface{}) (interface{}, error) {
+
+	templateParameterSet := data.(*models.TemplateParameterSet)
+
+	if err := db.Create(templateParameterSet).Error; err != nil {
+		return nil, err
+	}
+
+	return templateParameterSet, nil
+
+}
+
+func (_ *TemplateParameterSetLogic) Update(db *gorm.DB, id string, data interface{}) (interface{}, error) {
+
+	templateParameterSet := data.(*models.TemplateParameterSet)
+	templateParameterSet.ID, _ = strconv.Atoi(id)
+
+	if err := db.Save(templateParameterSet).Error; err != nil {
+		return nil, err
+	}
+
+	return templateParameterSet, nil
+
+}
+
+func (_ *TemplateParameterSetLogic) Delete(db *gorm.DB, id string) error {
+
+	templateParameterSet := &models.TemplateParameterSet{}
+
+	if err := db.First(&templateParameterSet, id).Error; err != nil {
+		return err
+	}
+
+	if err := db.Delete(&templateParameterSet).Error; err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+func (_ *TemplateParameterSetLogic) Patch(_ *gorm.DB, _ string, _ string) (interface{}, error) {
+	return nil, nil
+}
+
+func (_ *TemplateParameterSetLogic) Options(db *gorm.DB) error {
+	return nil
+}
+
+// templateParameterSets returns the global parameter sets and the ones attached to the template, in the order they are applied.
+func templateParameterSets(db *gorm.DB, template *models.Template) ([]*models.ParameterSet, error) {
+	templateParameterSets := []*models.TemplateParameterSet{}
+	if err := db.Where(&models.TemplateParameterSet{TemplateID: template.ID}).Find(&templateParameterSets).Error; err != nil {
+		return nil, err
+	}
+
+	parameterSetIDs := []int{}
+	for _, templateParameterSet := range templateParameterSets {
+		parameterSetIDs = append(parameterSetIDs, templateParameterSet.ParameterSetID)
+	}
+
+	parameterSets := []*models.ParameterSet{}
+	query := db.Where(&models.ParameterSet{Global: true})
+	if len(parameterSetIDs) > 0 {
+		query = query.Or("id in (?)", parameterSetIDs)
+	}
+	if err := query.Find(&parameterSets).Error; err != nil {
+		return nil, err
+	}
+
+	sort.Stable(parameterSetsByPriority(parameterSets))
+
+	return parameterSets, nil
+}
+
+func (_ *ParameterSetLogic) GetDesignKey() string {
+	return "parameter_sets"
+}
+
+func (_ *ParameterSetLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{}
+}
+
+func (this *ParameterSetLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+	parameterSets := []*models.ParameterSet{}
+	if err := db.Select("*").Find(&parameterSets).Error; err != nil {
+		return "", nil, err
+	}
+	return this.GetDesignKey(), parameterSets, nil
+}
+
+func (_ *ParameterSetLogic) DeleteFromDesign(db *gorm.DB) error {
+	return db.Exec("delete from parameter_sets;").Error
+}
+
+func (this *ParameterSetLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.ParameterSet{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, parameterSet := range container {
+			parameterSet.TemplateParameterSets = nil
+			if err := db.Create(parameterSet).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *ParameterSetLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.ParameterSet{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, parameterSet := range container {
+			parameterSet.TemplateParameterSets = nil
+			if err := db.Save(parameterSet).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (_ *TemplateParameterSetLogic) GetDesignKey() string {
+	return "template_parameter_sets"
+}
+
+func (_ *TemplateParameterSetLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{
+		"template_id":      TemplateLogicInstance.GetDesignKey(),
+		"parameter_set_id": ParameterSetLogicInstance.GetDesignKey(),
+	}
+}
+
+func (this *TemplateParameterSetLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+	templateParameterSets := []*models.TemplateParameterSet{}
+	if err := db.Select("*").Find(&templateParameterSets).Error; err != nil {
+		return "", nil, err
+	}
+	return this.GetDesignKey(), templateParameterSets, nil
+}
+
+func (_ *TemplateParameterSetLogic) DeleteFromDesign(db *gorm.DB) error {
+	return db.Exec("delete from template_parameter_sets;").Error
+}
+
+func (this *TemplateParameterSetLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateParameterSet{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateParameterSet := range container {
+			if err := db.Create(templateParameterSet).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *TemplateParameterSetLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateParameterSet{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateParameterSet := range container {
+			if err := db.Save(templateParameterSet).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var ParameterSetLogicInstance = &ParameterSetLogic{}
+var TemplateParameterSetLogicInstance = &TemplateParameterSetLogic{}
+
+func init() {
+	extension.RegisterDesignAccessor(ParameterSetLogicInstance)
+	extension.RegisterDesignAccessor(TemplateParameterSetLogicInstance)
+}
@@ -0,0 +1,71 @@
+package logics
+
+import (
+	"fmt"
+	"github.com/qb0C80aE/clay/models"
+)
+
+const (
+	TemplateExternalParametersKey            = "TemplateExternalParameters"
+	TemplateParameterSetsKey                 = "Parameters"
+	TemplateParameterSourceExternalParameter = "template_external_parameters"
+	TemplateParameterSourceParameterSet      = "parameter_sets"
+)
+
+type TemplateParameters struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Sources    map[string]string      `json:"sources"`
+	Warnings   []string               `json:"warnings"`
+}
+
+func (this *TemplateParameters) set(key string, value interface{}, source string) {
+	if previousSource, exists := this.Sources[key]; exists {
+		this.Warnings = append(this.Warnings, fmt.Sprintf("%s from %s is overwritten by %s.", key, previousSource, source))
+	}
+	this.Parameters[key] = value
+	this.Sources[key] = source
+}
+
+// withExternalParameters returns a copy of the generated parameters with the external parameters of the template,
+// which the parameters override or add to.
+// The parameter sets are merged in order into another key, and the external parameters and the parameters override them.
+func (this *TemplateParameters) withExternalParameters(template *models.Template, parameterSets []*models.ParameterSet, parameters map[string]interface{}) (*TemplateParameters, error) {
+	result := &TemplateParameters{
+		Parameters: map[string]interface{}{},
+		Sources:    map[string]string{},
+		Warnings:   append([]string{}, this.Warnings...),
+	}
+	for key, value := range this.Parameters {
+		result.Parameters[key] = value
+	}
+	for key, source := range this.Sources {
+		result.Sources[key] = source
+	}
+
+	templateExternalParameterMap := make(map[string]interface{})
+	for _, templateExternalParameter := range template.TemplateExternalParameters {
+		value, err := decodeTemplateExternalParameter(templateExternalParameter)
+		if err != nil {
+			return nil, err
+		}
+		templateExternalParameterMap[templateExternalParameter.Name] = value
+	}
+	for name, value := range parameters {
+		templateExternalParameterMap[name] = value
+	}
+
+	parameterSetMap := make(map[string]interface{})
+	for _, parameterSet := range parameterSets {
+		for name, value := range parameterSet.Parameters {
+			parameterSetMap[name] = value
+		}
+	}
+	for name, value := range templateExternalParameterMap {
+		parameterSetMap[name] = value
+	}
+
+	result.set(TemplateExternalParametersKey, templateExternalParameterMap, TemplateParameterSourceExternalParameter)
+	result.set(TemplateParameterSetsKey, parameterSetMap, TemplateParameterSourceParameterSet)
+
+	return result, nil
+}
@@ -0,0 +1,311 @@
+package logics
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	dbpkg "github.com/qb0C80aE/clay/db"
+	"github.com/qb0C80aE/clay/extension"
+	"github.com/qb0C80aE/clay/models"
+	"github.com/qb0C80aE/clay/utils/mapstruct"
+	"strconv"
+	"strings"
+)
+
+type TemplateRevisionLogic struct {
+}
+
+func templateContentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func latestTemplateRevision(db *gorm.DB, templateID int) (*models.TemplateRevision, error) {
+	templateRevisions := []*models.TemplateRevision{}
+	if err := db.Where(&models.TemplateRevision{TemplateID: templateID}).Order("revision desc").Limit(1).Find(&templateRevisions).Error; err != nil {
+		return nil, err
+	}
+	if len(templateRevisions) == 0 {
+		return nil, nil
+	}
+	return templateRevisions[0], nil
+}
+
+// recordTemplateRevision stores the content of the template as a new revision, unless it's same as the latest one.
+func recordTemplateRevision(db *gorm.DB, template *models.Template) error {
+	latest, err := latestTemplateRevision(db, template.ID)
+	if err != nil {
+		return err
+	}
+
+	contentHash := templateContentHash(template.TemplateContent)
+	revision := 1
+	if latest != nil {
+		if latest.ContentHash == contentHash {
+			return nil
+		}
+		revision = latest.Revision + 1
+	}
+
+	author := ""
+	if value, exists := db.Get(dbpkg.AuthorKey); exists {
+		author = fmt.Sprint(value)
+	}
+
+	templateRevision := &models.TemplateRevision{
+		TemplateID:      template.ID,
+		Revision:        revision,
+		Author:          author,
+		ContentHash:     contentHash,
+		TemplateContent: template.TemplateContent,
+	}
+
+	return db.Create(templateRevision).Error
+}
+
+// recordTemplateRevisions records the contents of the templates loaded with a design, after their revisions are loaded.
+func recordTemplateRevisions(db *gorm.DB) error {
+	templates := []*models.Template{}
+	if err := db.Select("*").Order("id").Find(&templates).Error; err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		if err := recordTemplateRevision(db, template); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (_ *TemplateLogic) GetRevisions(db *gorm.DB, id string) ([]*models.TemplateRevision, error) {
+	templateID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Parameter. id must be a number, but '%s'.", id)
+	}
+
+	template := &models.Template{}
+	if err := db.First(template, templateID).Error; err != nil {
+		return nil, err
+	}
+
+	templateRevisions := []*models.TemplateRevision{}
+	if err := db.Where(&models.TemplateRevision{TemplateID: template.ID}).Order("revision").Find(&templateRevisions).Error; err != nil {
+		return nil, err
+	}
+
+	return templateRevisions, nil
+}
+
+func (_ *TemplateLogic) GetRevision(db *gorm.DB, id string, revision string) (*models.TemplateRevision, error) {
+	templateID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Parameter. id must be a number, but '%s'.", id)
+	}
+	revisionNumber, err := strconv.Atoi(revision)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Parameter. revision must be a number, but '%s'.", revision)
+	}
+
+	// The conditions are explicit, because gorm drops the zero fields of struct conditions.
+	templateRevision := &models.TemplateRevision{}
+	if err := db.Where("template_id = ? and revision = ?", templateID, revisionNumber).First(templateRevision).Error; err != nil {
+		return nil, err
+	}
+
+	return templateRevision, nil
+}
+
+// RenderRevision renders the content of the revision with the present parameters of the template.
+func (this *TemplateLogic) RenderRevision(db *gorm.DB, id string, revision string, parameters map[string]interface{}, strict bool) (string, error) {
+	templateRevision, err := this.GetRevision(db, id, revision)
+	if err != nil {
+		return "", err
+	}
+
+	template := &models.Template{}
+	if err := db.Preload("TemplateExternalParameters").Select("*").First(template, templateRevision.TemplateID).Error; err != nil {
+		return "", err
+	}
+	template.TemplateContent = templateRevision.TemplateContent
+
+	return renderTemplate(db, template, parameters, strict)
+}
+
+// DiffRevisions returns the line diff from the revision to the other revision, with a space, "-" or "+" at the head of each line.
+func (this *TemplateLogic) DiffRevisions(db *gorm.DB, id string, revision string, other string) (string, error) {
+	templateRevision, err := this.GetRevision(db, id, revision)
+	if err != nil {
+		return "", err
+	}
+	otherTemplateRevision, err := this.GetRevision(db, id, other)
+	if err != nil {
+		return "", err
+	}
+
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "--- revision %d\n", templateRevision.Revision)
+	fmt.Fprintf(&doc, "+++ revision %d\n", otherTemplateRevision.Revision)
+	for _, line := range diffLines(splitLines(templateRevision.TemplateContent), splitLines(otherTemplateRevision.TemplateContent)) {
+		doc.WriteString(line)
+		doc.WriteByte('\n')
+	}
+
+	return doc.String(), nil
+}
+
+// RestoreRevision sets the content of the revision to the template, which is stored as a new revision.
+func (this *TemplateLogic) RestoreRevision(db *gorm.DB, id string, revision string) (*models.Template, error) {
+	templateRevision, err := this.GetRevision(db, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.Template{}
+	if err := db.First(template, templateRevision.TemplateID).Error; err != nil {
+		return nil, err
+	}
+
+	template.TemplateContent = templateRevision.TemplateContent
+	if err := db.Model(template).Update("template_content", template.TemplateContent).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recordTemplateRevision(db, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines compares the lines by the longest common subsequence.
+func diffLines(a []string, b []string) []string {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	result := []string{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, " "+a[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			result = append(result, "-"+a[i])
+			i++
+		default:
+			result = append(result, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		result = append(result, "+"+b[j])
+	}
+
+	return result
+}
+
+// storeTemplateRevision keeps the CreatedAt of the revision, which gorm overwrites when it creates a record.
+func storeTemplateRevision(db *gorm.DB, templateRevision *models.TemplateRevision, create bool) error {
+	createdAt := templateRevision.CreatedAt
+	store := db.Save
+	if create {
+		store = db.Create
+	}
+	if err := store(templateRevision).Error; err != nil {
+		return err
+	}
+	return db.Model(templateRevision).UpdateColumn("created_at", createdAt).Error
+}
+
+func (_ *TemplateRevisionLogic) GetDesignKey() string {
+	return "template_revisions"
+}
+
+func (_ *TemplateRevisionLogic) GetDesignDependencies() map[string]string {
+	return map[string]string{
+		"template_id": TemplateLogicInstance.GetDesignKey(),
+	}
+}
+
+// The revisions are optional content of the design.
+func (this *TemplateRevisionLogic) ExtractFromDesign(db *gorm.DB) (string, interface{}, error) {
+	if optional, exists := db.Get(DesignOptionalContentKey); !exists || optional != true {
+		return this.GetDesignKey(), nil, nil
+	}
+
+	templateRevisions := []*models.TemplateRevision{}
+	if err := db.Select("*").Order("template_id").Order("revision").Find(&templateRevisions).Error; err != nil {
+		return "", nil, err
+	}
+	return this.GetDesignKey(), templateRevisions, nil
+}
+
+func (_ *TemplateRevisionLogic) DeleteFromDesign(db *gorm.DB) error {
+	return db.Exec("delete from template_revisions;").Error
+}
+
+func (this *TemplateRevisionLogic) LoadToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateRevision{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateRevision := range container {
+			if err := storeTemplateRevision(db, templateRevision, true); err != nil {
+				return err
+			}
+		}
+	}
+	return recordTemplateRevisions(db)
+}
+
+func (this *TemplateRevisionLogic) MergeToDesign(db *gorm.DB, data interface{}) error {
+	container := []*models.TemplateRevision{}
+	design := data.(*models.Design)
+	if value, exists := design.Content[this.GetDesignKey()]; exists {
+		if err := mapstruct.MapToStruct(value.([]interface{}), &container); err != nil {
+			return err
+		}
+		for _, templateRevision := range container {
+			if err := storeTemplateRevision(db, templateRevision, false); err != nil {
+				return err
+			}
+		}
+	}
+	return recordTemplateRevisions(db)
+}
+
+var TemplateRevisionLogicInstance = &TemplateRevisionLogic{}
+
+func init() {
+	extension.RegisterDesignAccessor(TemplateRevisionLogicInstance)
+}
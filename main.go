@@ -11,6 +11,10 @@ import (
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrate(os.Args[2:]))
+	}
+
 	host := "localhost"
 	port := "8080"
 
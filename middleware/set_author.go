@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	dbpkg "github.com/qb0C80aE/clay/db"
+)
+
+const AuthorHeader = "X-Clay-Author"
+
+// SetAuthorToDB must be used after SetDBtoContext.
+func SetAuthorToDB() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if author := c.Request.Header.Get(AuthorHeader); author != "" {
+			dbpkg.SetDBInstance(c, dbpkg.DBInstance(c).Set(dbpkg.AuthorKey, author))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/qb0C80aE/clay/db"
+	"github.com/qb0C80aE/clay/submodules"
+)
+
+const migrateUsage = "usage: clay migrate up|down [steps]|status"
+
+func migrate(args []string) int {
+	submodules.HookSubmodules()
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	database := db.Open()
+	defer database.Close()
+	database.LogMode(false)
+
+	switch args[0] {
+	case "up":
+		migrations, err := db.MigrateUp(database)
+		for _, migration := range migrations {
+			fmt.Printf("up   %d %s\n", migration.GetVersion(), migration.GetName())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			value, err := strconv.Atoi(args[1])
+			if err != nil || value < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+			steps = value
+		}
+		migrations, err := db.MigrateDown(database, steps)
+		for _, migration := range migrations {
+			fmt.Printf("down %d %s\n", migration.GetVersion(), migration.GetName())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "status":
+		statuses, err := db.GetMigrationStatus(database)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("applied %d %s (%s)\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("pending %d %s\n", status.Version, status.Name)
+			}
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -1,10 +1,46 @@
 package models
 
+import (
+	"github.com/qb0C80aE/clay/extension"
+	"time"
+)
+
+const DesignFormatVersion = 1
+
 type Design struct {
-	Content map[string]interface{} `json:"content"`
+	FormatVersion  int                    `json:"format_version,omitempty"`
+	SchemaVersions map[string]int         `json:"schema_versions,omitempty"`
+	Content        map[string]interface{} `json:"content"`
+}
+
+type DesignSnapshot struct {
+	ID        int       `json:"id" form:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	Name      string    `json:"name" form:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Content   string    `json:"-"`
+	Design    *Design   `json:"design,omitempty" sql:"-"`
+}
+
+type DesignDiff struct {
+	Resources map[string]*ResourceDiff `json:"resources"`
+}
+
+type ResourceDiff struct {
+	Added    []interface{} `json:"added"`
+	Removed  []interface{} `json:"removed"`
+	Modified []*RecordDiff `json:"modified"`
+}
+
+type RecordDiff struct {
+	ID     interface{} `json:"id"`
+	Fields []string    `json:"fields"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
 }
 
 var DesignModel = &Design{}
+var DesignSnapshotModel = &DesignSnapshot{}
 
 func init() {
+	extension.RegisterModelType(DesignSnapshotModel)
 }
@@ -4,10 +4,19 @@ import (
 	"github.com/qb0C80aE/clay/extension"
 )
 
+const (
+	TemplateExternalParameterTypeString = "string"
+	TemplateExternalParameterTypeInt    = "int"
+	TemplateExternalParameterTypeBool   = "bool"
+	TemplateExternalParameterTypeJSON   = "json"
+)
+
+// An empty Type is same as TemplateExternalParameterTypeString, which the parameters had before they were typed.
 type TemplateExternalParameter struct {
 	ID         int    `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
 	TemplateID int    `json:"template_id" gorm:"index" sql:"type:integer references templates(id) on delete cascade"`
 	Name       string `json:"name"`
+	Type       string `json:"type,omitempty"`
 	Value      string `json:"value"`
 }
 
@@ -22,6 +31,6 @@ var TemplateExternalParameterModel = &TemplateExternalParameter{}
 var TemplateModel = &Template{}
 
 func init() {
-	extension.RegisterModelType(TemplateExternalParameterModel)
 	extension.RegisterModelType(TemplateModel)
+	extension.RegisterModelType(TemplateExternalParameterModel)
 }
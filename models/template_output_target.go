@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/qb0C80aE/clay/extension"
+	"time"
+)
+
+const (
+	TemplateOutputTargetTypeFile    = "file"
+	TemplateOutputTargetTypeWebhook = "webhook"
+)
+
+const (
+	TemplateOutputTargetStatusSucceeded = "succeeded"
+	TemplateOutputTargetStatusFailed    = "failed"
+)
+
+// Destination is a path under the output root for file targets, or a URL for webhook targets.
+// Status, Error and PublishedAt record the last publish.
+type TemplateOutputTarget struct {
+	ID          int        `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	TemplateID  int        `json:"template_id" gorm:"index" sql:"type:integer references templates(id) on delete cascade"`
+	Type        string     `json:"type"`
+	Destination string     `json:"destination"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+var TemplateOutputTargetModel = &TemplateOutputTarget{}
+
+func init() {
+	extension.RegisterModelType(TemplateOutputTargetModel)
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/qb0C80aE/clay/extension"
+)
+
+type ParameterSetParameters map[string]interface{}
+
+func (this ParameterSetParameters) Value() (driver.Value, error) {
+	if this == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(this)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (this *ParameterSetParameters) Scan(value interface{}) error {
+	var data []byte
+	switch value := value.(type) {
+	case nil:
+		*this = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("Cannot scan %T into the parameters.", value)
+	}
+	return json.Unmarshal(data, this)
+}
+
+// Global parameter sets apply to every template, and the others apply to the templates they are attached to.
+type ParameterSet struct {
+	ID                    int                     `json:"id" form:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	Name                  string                  `json:"name" form:"name"`
+	Global                bool                    `json:"global" form:"global"`
+	Priority              int                     `json:"priority" form:"priority"`
+	Parameters            ParameterSetParameters  `json:"parameters" sql:"type:text"`
+	TemplateParameterSets []*TemplateParameterSet `json:"template_parameter_sets"`
+}
+
+type TemplateParameterSet struct {
+	ID             int `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	TemplateID     int `json:"template_id" gorm:"index" sql:"type:integer references templates(id) on delete cascade"`
+	ParameterSetID int `json:"parameter_set_id" gorm:"index" sql:"type:integer references parameter_sets(id) on delete cascade"`
+}
+
+var ParameterSetModel = &ParameterSet{}
+var TemplateParameterSetModel = &TemplateParameterSet{}
+
+func init() {
+	extension.RegisterModelType(ParameterSetModel)
+	extension.RegisterModelType(TemplateParameterSetModel)
+}
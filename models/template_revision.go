@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/qb0C80aE/clay/extension"
+	"time"
+)
+
+// Revision is numbered from 1 in each template, and a new one is stored whenever the content of the template changes.
+type TemplateRevision struct {
+	ID              int       `json:"id" gorm:"primary_key;AUTO_INCREMENT"`
+	TemplateID      int       `json:"template_id" gorm:"index" sql:"type:integer references templates(id) on delete cascade"`
+	Revision        int       `json:"revision"`
+	Author          string    `json:"author"`
+	CreatedAt       time.Time `json:"created_at"`
+	ContentHash     string    `json:"content_hash"`
+	TemplateContent string    `json:"template_content"`
+}
+
+var TemplateRevisionModel = &TemplateRevision{}
+
+func init() {
+	extension.RegisterModelType(TemplateRevisionModel)
+}
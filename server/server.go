@@ -13,6 +13,7 @@ func Setup(db *gorm.DB) *gin.Engine {
 	submodules.HookSubmodules()
 	r := gin.Default()
 	r.Use(middleware.SetDBtoContext(db))
+	r.Use(middleware.SetAuthorToDB())
 	router.Initialize(r)
 	return r
 }
@@ -20,6 +20,9 @@ func ParseCIDR(cidr string) (*Ipv4Address, error) {
 		return nil, err
 	}
 	ip = ip.To4()
+	if ip == nil || len(ipNet.Mask) != net.IPv4len {
+		return nil, fmt.Errorf("%s is not an IPv4 CIDR.", cidr)
+	}
 	result := &Ipv4Address{}
 	result.v4address = (uint32(ip[0])<<24 | (uint32(ip[1]) << 16) | (uint32(ip[2]) << 8) | uint32(ip[3]))
 	prefix, _ := ipNet.Mask.Size()